@@ -1,8 +1,13 @@
 package backend
 
 import (
-	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"context"
+	"io"
 	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/pkg/mempressure"
 )
 
 type LoaderFunc[V any] func(key string) (V, time.Duration, error)
@@ -13,5 +18,82 @@ type Cache[V any] interface {
 	Set(key string, value V, options ...option.OptFnc) error
 	Delete(key string, options ...option.DelOptFnc) error
 	Clear(options ...option.ClrOptFnc) error
+	Len() int
+	Keys() []string
+	// Range calls fn for every live entry until fn returns false. Shards are
+	// visited one at a time and each shard is copied under its own lock before
+	// fn runs, so fn may safely call back into the cache. Mutations made
+	// concurrently to a shard that has not been visited yet are observed,
+	// mutations to already visited shards are not; no global snapshot is taken.
+	Range(fn func(key string, value V) bool)
 	Close() error
 }
+
+// GracefulCloser is implemented by backends whose Close waits for their
+// background goroutines. CloseContext bounds that wait by ctx.
+type GracefulCloser interface {
+	CloseContext(ctx context.Context) error
+}
+
+// InvalidationReporter is implemented by backends that can subscribe to an
+// invalidation bus. The state is StateDisabled when none is configured.
+type InvalidationReporter interface {
+	InvalidationState() invalidation.ConnectionState
+}
+
+// Flusher is implemented by backends that publish invalidations from a
+// background queue. Flush waits until the queue is drained or ctx ends.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// EpochReporter is implemented by backends that count clears. Epoch is never
+// zero and grows with every local or received Clear; a Set tagged with
+// option.WithEpoch and an older epoch fails with constant.ErrStaleEpoch.
+type EpochReporter interface {
+	Epoch() uint64
+}
+
+// Stamper is implemented by backends that stamp writes to order them against
+// received invalidations. Stamp returns the stamp a write starting now would
+// get, zero when writes are not stamped; see option.WithStamp.
+type Stamper interface {
+	Stamp() int64
+}
+
+// Health is a point-in-time view of a backend and its invalidation
+// subscription, suitable for readiness and liveness probes.
+type Health struct {
+	Backend      string              `json:"backend"`
+	Closed       bool                `json:"closed"`
+	Invalidation invalidation.Health `json:"invalidation"`
+}
+
+// HealthReporter is implemented by backends that report their health.
+// WaitReady blocks until the invalidation subscription is established, or
+// returns immediately when none is configured.
+type HealthReporter interface {
+	Health() Health
+	WaitReady(ctx context.Context) error
+}
+
+// Snapshotter is implemented by backends that can stream their live entries,
+// including absolute expirations, to a writer and load them back on start.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// MemoryPressureReporter is implemented by backends that shed entries when
+// the process heap approaches its soft limit.
+type MemoryPressureReporter interface {
+	MemoryPressureStats() mempressure.Stats
+}
+
+// Resizer is implemented by backends whose capacity, default TTL and shard
+// count can be changed at runtime without dropping their contents.
+type Resizer interface {
+	Resize(capacity int) error
+	SetDefaultTTL(ttl time.Duration)
+	Reshard(shardCount int) error
+}
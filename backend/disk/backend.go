@@ -0,0 +1,414 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/codec"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+	"github.com/halilbulentorhon/invacache-go/pkg/singleflight"
+)
+
+type diskBackend[V any] struct {
+	ctx          context.Context
+	store        *diskStore
+	invalidator  *invalidation.Coordinator
+	delivery     option.DeliveryDefaults
+	logger       logger.Logger
+	codec        codec.Codec[V]
+	cancel       context.CancelFunc
+	singleFlight singleflight.Group[V]
+	closeErr     error
+	wg           sync.WaitGroup
+	defaultTTL   time.Duration
+	closeOnce    sync.Once
+	closed       atomic.Bool
+	// epoch counts clears, starting at 1 like the in-memory backend's.
+	epoch atomic.Uint64
+}
+
+func (d *diskBackend[V]) Get(key string) (V, error) {
+	value, found, err := d.get(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	if !found {
+		var zero V
+		return zero, constant.ErrNotFound
+	}
+	return value, nil
+}
+
+func (d *diskBackend[V]) get(key string) (V, bool, error) {
+	var zero V
+	data, found, err := d.store.get(key)
+	if err != nil || !found {
+		return zero, false, err
+	}
+
+	value, err := d.codec.Unmarshal(data)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to decode value for key %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (d *diskBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V]) (V, error) {
+	if value, found, err := d.get(key); err == nil && found {
+		return value, nil
+	}
+	if d.closed.Load() {
+		var zero V
+		return zero, constant.ErrClosed
+	}
+
+	// A clear while the loader runs may have removed what it read.
+	epoch := d.epoch.Load()
+	value, ttl, err := d.singleFlight.Do(key, func() (V, time.Duration, error) {
+		return loader(key)
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	if existing, found, err := d.get(key); err == nil && found {
+		return existing, nil
+	}
+
+	setErr := d.set(key, value, option.WithTTL(ttl), option.WithEpoch(epoch))
+	if setErr != nil && !errors.Is(setErr, constant.ErrStaleEpoch) {
+		var zero V
+		return zero, setErr
+	}
+	return value, nil
+}
+
+func (d *diskBackend[V]) Set(key string, value V, options ...option.OptFnc) error {
+	if err := d.set(key, value, options...); err != nil {
+		return err
+	}
+
+	cfg := option.ApplyOptions(options)
+	return d.publishInvalidation(invalidation.Message{Key: key}, cfg.Delivery.Or(d.delivery.Set))
+}
+
+func (d *diskBackend[V]) set(key string, value V, options ...option.OptFnc) error {
+	if d.closed.Load() {
+		return constant.ErrClosed
+	}
+	cfg := option.ApplyOptions(options)
+
+	var expiresAt time.Time
+	if !cfg.NoExpiration {
+		ttl := cfg.TTL
+		if ttl == 0 {
+			ttl = d.defaultTTL
+		}
+		if ttl > 0 {
+			expiresAt = time.Now().Add(ttl)
+		}
+	}
+
+	data, err := d.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for key %s: %w", key, err)
+	}
+	if cfg.Epoch == 0 {
+		return d.store.set(key, data, expiresAt)
+	}
+	err = d.store.setUnless(key, data, expiresAt, func() bool { return cfg.Epoch < d.epoch.Load() })
+	if errors.Is(err, errStale) {
+		return fmt.Errorf("%w: %s", constant.ErrStaleEpoch, key)
+	}
+	return err
+}
+
+func (d *diskBackend[V]) Delete(key string, options ...option.DelOptFnc) error {
+	if d.closed.Load() {
+		return constant.ErrClosed
+	}
+	if err := d.store.delete(key); err != nil {
+		return err
+	}
+
+	cfg := option.ApplyDeleteOptions(options)
+	return d.publishInvalidation(invalidation.Message{Key: key}, cfg.Delivery.Or(d.delivery.Delete))
+}
+
+func (d *diskBackend[V]) Clear(options ...option.ClrOptFnc) error {
+	if d.closed.Load() {
+		return constant.ErrClosed
+	}
+	cfg := option.ApplyClearOptions(options)
+	// The epoch moves first, so writes tagged with an older one are refused.
+	epoch := d.epoch.Add(1)
+	if err := d.store.clear(cfg.Matches); err != nil {
+		return err
+	}
+
+	return d.publishInvalidation(invalidation.Message{Namespace: cfg.Namespace, Epoch: epoch}, cfg.Delivery.Or(d.delivery.Clear))
+}
+
+// Epoch returns the current clear epoch; see backend.EpochReporter.
+func (d *diskBackend[V]) Epoch() uint64 {
+	return d.epoch.Load()
+}
+
+func (d *diskBackend[V]) Health() backend.Health {
+	return backend.Health{
+		Backend:      constant.DiskBackend,
+		Closed:       d.closed.Load(),
+		Invalidation: d.invalidator.Health(),
+	}
+}
+
+func (d *diskBackend[V]) WaitReady(ctx context.Context) error {
+	return d.invalidator.WaitReady(ctx)
+}
+
+func (d *diskBackend[V]) Flush(ctx context.Context) error {
+	return d.invalidator.Flush(ctx)
+}
+
+func (d *diskBackend[V]) InvalidationState() invalidation.ConnectionState {
+	return d.invalidator.State()
+}
+
+func (d *diskBackend[V]) publishInvalidation(message invalidation.Message, delivery option.Delivery) error {
+	if d.invalidator == nil {
+		return nil
+	}
+	message.Stamp = d.invalidator.Stamp()
+	switch delivery {
+	case option.DeliveryAsync:
+		return d.invalidator.Publish(message)
+	case option.DeliverySync:
+		return d.invalidator.PublishSync(message)
+	default:
+		return nil
+	}
+}
+
+func (d *diskBackend[V]) handleInvalidationMessage(key string) error {
+	if invalidation.IsClearEvent(key) {
+		return d.Clear(option.WithClearDelivery(option.DeliveryLocal))
+	}
+	return d.Delete(key, option.WithDeleteDelivery(option.DeliveryLocal))
+}
+
+// handleClearMessage applies a peer's clear, moving the local epoch past both
+// its own value and the peer's.
+func (d *diskBackend[V]) handleClearMessage(namespace string, epoch uint64) error {
+	for {
+		current := d.epoch.Load()
+		if d.epoch.CompareAndSwap(current, max(current+1, epoch)) {
+			break
+		}
+	}
+	return d.store.clear(option.ClearConfig{Namespace: namespace}.Matches)
+}
+
+func (d *diskBackend[V]) Len() int {
+	return len(d.store.liveKeys())
+}
+
+func (d *diskBackend[V]) Keys() []string {
+	return d.store.liveKeys()
+}
+
+func (d *diskBackend[V]) Range(fn func(key string, value V) bool) {
+	for _, key := range d.store.liveKeys() {
+		value, found, err := d.get(key)
+		if err != nil {
+			d.logger.Warn("failed to read key during range", "key", key, "error", err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+func (d *diskBackend[V]) runSweeper(interval time.Duration) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			if swept := d.store.sweepExpired(); swept > 0 {
+				d.logger.Debug("swept expired disk entries", "count", swept)
+			}
+		}
+	}
+}
+
+func (d *diskBackend[V]) Close() error {
+	return d.CloseContext(context.Background())
+}
+
+// CloseContext stops accepting writes, flushes queued invalidations, cancels
+// the sweeper and the invalidation subscription and waits for them to exit,
+// then syncs the store and closes the invalidation driver. Without a deadline
+// on ctx, the flush gives up after constant.DefaultCloseFlushTimeout. If ctx
+// ends first the driver is still closed and ctx's error is returned. Later
+// calls return the result of the first one.
+func (d *diskBackend[V]) CloseContext(ctx context.Context) error {
+	d.closeOnce.Do(func() {
+		d.closeErr = d.shutdown(ctx)
+	})
+	return d.closeErr
+}
+
+func (d *diskBackend[V]) shutdown(ctx context.Context) error {
+	d.logger.Info("closing disk cache")
+	d.closed.Store(true)
+
+	if err := d.invalidator.FlushOnClose(ctx); err != nil {
+		d.logger.Warn("pending invalidations were not published", "error", err)
+	}
+
+	d.cancel()
+	waitErr := d.waitForBackground(ctx)
+	if waitErr != nil {
+		d.logger.Warn("background goroutines did not stop in time", "error", waitErr)
+	}
+
+	storeErr := d.store.close()
+	if storeErr != nil {
+		d.logger.Error("failed to sync disk store", "error", storeErr)
+	}
+
+	if d.invalidator != nil {
+		if err := d.invalidator.Close(); err != nil {
+			d.logger.Error("failed to close invalidator", "error", err)
+			return err
+		}
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	if storeErr != nil {
+		return storeErr
+	}
+	d.logger.Info("disk cache closed successfully")
+	return nil
+}
+
+func (d *diskBackend[V]) waitForBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func NewDiskBackend[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	log := cfg.NewLogger("disk-cache")
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.Disk == nil {
+		cfg.Backend.Disk = &config.DiskConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	diskCfg := cfg.Backend.Disk
+	if diskCfg.Dir == constant.EmptyString {
+		return nil, fmt.Errorf("disk backend requires a directory")
+	}
+
+	valueCodec, err := codec.New[V](cfg.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec: %w", err)
+	}
+
+	store, err := openDiskStore(diskCfg.Dir, diskCfg.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("initializing disk cache",
+		"dir", diskCfg.Dir,
+		"max_bytes", diskCfg.MaxBytes,
+		"used_bytes", store.size(),
+		"skipped_files", store.skipped,
+		"sweeper_interval", diskCfg.SweeperInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	be := &diskBackend[V]{
+		ctx:          ctx,
+		store:        store,
+		logger:       log,
+		codec:        valueCodec,
+		cancel:       cancel,
+		singleFlight: singleflight.Group[V]{},
+		defaultTTL:   diskCfg.DefaultTTL,
+	}
+	be.epoch.Store(1)
+
+	be.wg.Add(1)
+	go be.runSweeper(diskCfg.SweeperInterval)
+
+	if cfg.Invalidation != nil {
+		log.Info("initializing invalidation", "type", cfg.Invalidation.Type)
+		inv := cfg.Invalidation.PubSub
+		var err error
+		if inv == nil {
+			inv, err = invalidation.NewInvalidatorWithLogger(cfg.Invalidation.Type, cfg.Invalidation.DriverConfig, log.With("driver", cfg.Invalidation.Type))
+		}
+		if err != nil {
+			cancel()
+			be.wg.Wait()
+			log.Error("failed to create invalidator", "type", cfg.Invalidation.Type, "error", err)
+			return nil, fmt.Errorf("failed to create invalidator: %w", err)
+		}
+		onGap := func() {
+			if err := be.Clear(option.WithClearDelivery(option.DeliveryLocal)); err != nil {
+				log.Warn("failed to clear cache after invalidation gap", "error", err)
+			}
+		}
+		opts := append(cfg.Invalidation.CoordinatorOptions(onGap), invalidation.WithClearHandler(be.handleClearMessage))
+		be.invalidator = invalidation.NewCoordinator(ctx, inv, log, opts...)
+		be.delivery = cfg.Invalidation.DeliveryDefaults()
+		log.Info("invalidation initialized successfully", "type", cfg.Invalidation.Type)
+		be.wg.Add(2)
+		go func() {
+			defer be.wg.Done()
+			be.invalidator.Run(be.handleInvalidationMessage)
+		}()
+		go func() {
+			defer be.wg.Done()
+			be.invalidator.RunPublisher()
+		}()
+	}
+
+	return be, nil
+}
@@ -0,0 +1,317 @@
+package disk
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type diskUser struct {
+	Name string
+	Age  int
+}
+
+func createTestCache[V any](t *testing.T, dir string) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			Disk: &config.DiskConfig{
+				Dir:             dir,
+				MaxBytes:        1 << 20,
+				SweeperInterval: time.Minute,
+			},
+		},
+	}
+
+	cache, err := NewDiskBackend[V](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	return cache
+}
+
+func TestNewDiskBackendRequiresDir(t *testing.T) {
+	if _, err := NewDiskBackend[string](config.InvaCacheConfig{}); err == nil {
+		t.Error("expected error without directory")
+	}
+}
+
+func TestDiskSetAndGet(t *testing.T) {
+	cache := createTestCache[diskUser](t, t.TempDir())
+	defer cache.Close()
+
+	if err := cache.Set("user:1", diskUser{Name: "john", Age: 30}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user, err := cache.Get("user:1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Name != "john" || user.Age != 30 {
+		t.Errorf("unexpected value: %+v", user)
+	}
+
+	_, err = cache.Get("missing")
+	if err != constant.ErrNotFound {
+		t.Errorf("expected key not found error, got %v", err)
+	}
+}
+
+func TestDiskSetWithTTL(t *testing.T) {
+	cache := createTestCache[string](t, t.TempDir())
+	defer cache.Close()
+
+	_ = cache.Set("key1", "value1", option.WithTTL(50*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := cache.Get("key1"); err == nil {
+		t.Error("expected key1 to expire")
+	}
+}
+
+func TestDiskSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	first := createTestCache[string](t, dir)
+	_ = first.Set("key1", "value1", option.WithNoExpiration())
+	_ = first.Set("key2", "value2", option.WithTTL(time.Hour))
+	_ = first.Close()
+
+	second := createTestCache[string](t, dir)
+	defer second.Close()
+
+	if second.Len() != 2 {
+		t.Errorf("expected 2 entries after restart, got %d", second.Len())
+	}
+	value, err := second.Get("key2")
+	if err != nil || value != "value2" {
+		t.Errorf("expected value2 after restart, got %q, %v", value, err)
+	}
+}
+
+func TestDiskDeleteClearAndRange(t *testing.T) {
+	cache := createTestCache[int](t, t.TempDir())
+	defer cache.Close()
+
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+	_ = cache.Set("c", 3)
+
+	_ = cache.Delete("a")
+	if _, err := cache.Get("a"); err == nil {
+		t.Error("expected a to be deleted")
+	}
+
+	sum := 0
+	cache.Range(func(_ string, value int) bool {
+		sum += value
+		return true
+	})
+	if sum != 5 {
+		t.Errorf("expected sum 5, got %d", sum)
+	}
+
+	_ = cache.Set("ns:a", 4)
+	_ = cache.Clear(option.WithNamespace("ns"))
+	if cache.Len() != 2 {
+		t.Errorf("expected only the namespace to be cleared, got %v", cache.Keys())
+	}
+
+	_ = cache.Clear()
+	if cache.Len() != 0 || len(cache.Keys()) != 0 {
+		t.Error("expected empty cache after clear")
+	}
+}
+
+func TestDiskGetOrLoad(t *testing.T) {
+	cache := createTestCache[string](t, t.TempDir())
+	defer cache.Close()
+
+	calls := 0
+	loader := func(key string) (string, time.Duration, error) {
+		calls++
+		return "loaded", time.Minute, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrLoad("key1", loader)
+		if err != nil || value != "loaded" {
+			t.Fatalf("unexpected result: %q, %v", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected loader to run once, ran %d times", calls)
+	}
+
+	_, err := cache.GetOrLoad("failing", func(string) (string, time.Duration, error) {
+		return "", 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected loader error")
+	}
+}
+
+type recordingPubSub struct {
+	handler   invalidation.InvalidationHandler
+	published []string
+	ready     chan struct{}
+	mu        sync.Mutex
+}
+
+func (r *recordingPubSub) Publish(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, key)
+	return nil
+}
+
+func (r *recordingPubSub) Subscribe(ctx context.Context, handler invalidation.InvalidationHandler) error {
+	r.mu.Lock()
+	r.handler = handler
+	r.mu.Unlock()
+	close(r.ready)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *recordingPubSub) Close() error {
+	return nil
+}
+
+func TestDiskInvalidation(t *testing.T) {
+	pubsub := &recordingPubSub{ready: make(chan struct{})}
+	invalidation.RegisterInvalidatorWithLogger("disk-test", func(any, logger.Logger) (invalidation.PubSub, error) {
+		return pubsub, nil
+	})
+
+	cfg := config.InvaCacheConfig{
+		Backend:      &config.BackendConfig{Disk: &config.DiskConfig{Dir: t.TempDir()}},
+		Invalidation: &config.InvalidationConfig{Type: "disk-test"},
+	}
+	cache, err := NewDiskBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	select {
+	case <-pubsub.ready:
+	case <-time.After(time.Second):
+		t.Fatal("subscription did not start")
+	}
+
+	_ = cache.Set("key1", "value1", option.WithInvalidation())
+	_ = cache.Set("key2", "value2")
+	if err = cache.(backend.Flusher).Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	pubsub.mu.Lock()
+	published := append([]string(nil), pubsub.published...)
+	handler := pubsub.handler
+	pubsub.mu.Unlock()
+	if len(published) != 1 || published[0] != "key1" {
+		t.Errorf("expected key1 to be published, got %v", published)
+	}
+
+	if err = handler("key2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = cache.Get("key2"); err == nil {
+		t.Error("expected key2 to be invalidated")
+	}
+
+	if err = handler(constant.EmptyString); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Error("expected clear event to empty the cache")
+	}
+}
+
+func TestDiskClearEpoch(t *testing.T) {
+	cache := createTestCache[string](t, t.TempDir())
+	defer cache.Close()
+	reporter := cache.(backend.EpochReporter)
+
+	epoch := reporter.Epoch()
+	if err := cache.Clear(option.WithNamespace("user")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reporter.Epoch() <= epoch {
+		t.Errorf("expected Clear to advance the epoch past %d, got %d", epoch, reporter.Epoch())
+	}
+	if err := cache.Set("user:1", "stale", option.WithEpoch(epoch)); !errors.Is(err, constant.ErrStaleEpoch) {
+		t.Errorf("expected ErrStaleEpoch, got %v", err)
+	}
+	if _, err := cache.Get("user:1"); err == nil {
+		t.Error("expected the stale write to be refused")
+	}
+	if err := cache.Set("user:1", "fresh", option.WithEpoch(reporter.Epoch())); err != nil {
+		t.Errorf("unexpected error for a current epoch: %v", err)
+	}
+
+	value, err := cache.GetOrLoad("user:2", func(string) (string, time.Duration, error) {
+		_ = cache.Clear()
+		return "loaded", time.Minute, nil
+	})
+	if err != nil || value != "loaded" {
+		t.Fatalf("unexpected result: %q, %v", value, err)
+	}
+	if _, err := cache.Get("user:2"); err == nil {
+		t.Error("expected a load that raced a clear not to be stored")
+	}
+}
+
+func TestDiskCloseStopsWrites(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	cache := createTestCache[string](t, t.TempDir())
+	if err := cache.Set("key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("expected a second Close to return the first result, got %v", err)
+	}
+	if !cache.(backend.HealthReporter).Health().Closed {
+		t.Error("expected Health to report the cache closed")
+	}
+
+	if err := cache.Set("other", "value"); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Set, got %v", err)
+	}
+	if err := cache.Delete("key"); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Delete, got %v", err)
+	}
+	if err := cache.Clear(); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Clear, got %v", err)
+	}
+	if _, err := cache.GetOrLoad("other", func(string) (string, time.Duration, error) {
+		return "loaded", time.Minute, nil
+	}); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from GetOrLoad, got %v", err)
+	}
+	if value, err := cache.Get("key"); err != nil || value != "value" {
+		t.Errorf("expected reads to keep working after Close, got %q, %v", value, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("expected the sweeper to have exited, got %d goroutines, started with %d", n, baseline)
+	}
+}
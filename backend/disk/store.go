@@ -0,0 +1,506 @@
+package disk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+const (
+	fileMagic      = "IVCD"
+	fileVersion    = 1
+	fileHeaderSize = len(fileMagic) + 1 + 8 + 4
+	tmpFilePrefix  = ".tmp-"
+	maxKeyLength   = 64 << 10
+)
+
+// errNotCacheFile marks a file that this store did not write; load leaves
+// such files alone.
+var errNotCacheFile = errors.New("not a cache file")
+
+// errStale is returned by setUnless when the write was refused.
+var errStale = errors.New("stale write")
+
+type diskEntry struct {
+	expiresAt time.Time
+	prev      *diskEntry
+	next      *diskEntry
+	key       string
+	size      int64
+}
+
+func (e *diskEntry) isExpired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// diskStore indexes the entry files under dir. Entry contents are read and
+// written outside mu; mu covers the index and the renames and removals of
+// entry files, which must happen in the same order as the index changes.
+type diskStore struct {
+	items     map[string]*diskEntry
+	head      *diskEntry
+	tail      *diskEntry
+	dir       string
+	maxBytes  int64
+	usedBytes int64
+	// skipped counts the files load could not read and left in place.
+	skipped int
+	mu      sync.Mutex
+	closed  bool
+}
+
+func openDiskStore(dir string, maxBytes int64) (*diskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	head := &diskEntry{}
+	tail := &diskEntry{}
+	head.next = tail
+	tail.prev = head
+
+	s := &diskStore{
+		items:    make(map[string]*diskEntry),
+		head:     head,
+		tail:     tail,
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type loadedEntry struct {
+	modTime time.Time
+	entry   *diskEntry
+}
+
+// load indexes the entries left by a previous run. Only the store's own
+// hashed subdirectories are read, and only files starting with the cache file
+// magic are ever removed, so unrelated files in dir are left untouched. A
+// subdirectory or file that cannot be read or removed is skipped.
+func (s *diskStore) load() error {
+	now := time.Now()
+	var loaded []loadedEntry
+
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to load cache directory: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || !isHashedName(dir.Name(), 2) {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(s.dir, dir.Name()))
+		if err != nil {
+			s.skipped++
+			continue
+		}
+		for _, file := range files {
+			entry, err := s.loadFile(filepath.Join(s.dir, dir.Name(), file.Name()), file, now)
+			if err != nil {
+				s.skipped++
+				continue
+			}
+			if entry != nil {
+				loaded = append(loaded, *entry)
+			}
+		}
+	}
+
+	// oldest writes first, so the most recently written entries end up at the head
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].modTime.Before(loaded[j].modTime)
+	})
+	for _, l := range loaded {
+		s.items[l.entry.key] = l.entry
+		s.addToHead(l.entry)
+		s.usedBytes += l.entry.size
+	}
+
+	return s.evict(0)
+}
+
+// loadFile returns the entry stored at path, or nil when the file is not a
+// live entry. Temporary, corrupt, misplaced and expired cache files are
+// removed; files without the cache file magic are skipped.
+func (s *diskStore) loadFile(path string, file fs.DirEntry, now time.Time) (*loadedEntry, error) {
+	if !file.Type().IsRegular() {
+		return nil, nil
+	}
+	name := file.Name()
+	if !strings.HasPrefix(name, tmpFilePrefix) && !isHashedName(name, sha256.Size*2-2) {
+		return nil, nil
+	}
+
+	key, expiresAt, err := readFileHeader(path)
+	switch {
+	case errors.Is(err, errNotCacheFile):
+		return nil, nil
+	case err != nil, strings.HasPrefix(name, tmpFilePrefix), s.path(key) != path:
+		return nil, removeIfExists(path)
+	}
+
+	info, err := file.Info()
+	if err != nil {
+		return nil, err
+	}
+	entry := &diskEntry{key: key, expiresAt: expiresAt, size: info.Size()}
+	if entry.isExpired(now) {
+		return nil, removeIfExists(path)
+	}
+	return &loadedEntry{modTime: info.ModTime(), entry: entry}, nil
+}
+
+func isHashedName(name string, length int) bool {
+	if len(name) != length {
+		return false
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *diskStore) get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	entry, exists := s.items[key]
+	if !exists {
+		s.mu.Unlock()
+		return nil, false, nil
+	}
+	if entry.isExpired(time.Now()) {
+		err := s.removeEntry(entry)
+		s.mu.Unlock()
+		return nil, false, err
+	}
+	s.moveToHead(entry)
+	s.mu.Unlock()
+
+	// Files are replaced by rename, so this reads a complete file: entry's, or
+	// one written since by a newer set.
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.forget(entry, false)
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	offset := fileHeaderSize + len(key)
+	if len(data) < offset {
+		return nil, false, s.forget(entry, true)
+	}
+	return data[offset:], true, nil
+}
+
+// forget drops entry from the index, and its file when remove is set, unless
+// the key has been written again since entry was read.
+func (s *diskStore) forget(entry *diskEntry, remove bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items[entry.key] != entry {
+		return nil
+	}
+	if remove {
+		return s.removeEntry(entry)
+	}
+	s.unlink(entry)
+	return nil
+}
+
+func (s *diskStore) set(key string, value []byte, expiresAt time.Time) error {
+	return s.setUnless(key, value, expiresAt, nil)
+}
+
+// setUnless is set, except that it returns errStale without writing when
+// stale, checked under the store lock, reports true. The file is written and
+// synced to a temporary file first, and renamed into place under the lock.
+func (s *diskStore) setUnless(key string, value []byte, expiresAt time.Time, stale func() bool) error {
+	size := int64(fileHeaderSize + len(key) + len(value))
+	if size > s.maxBytes {
+		return fmt.Errorf("entry for key %s is %d bytes, larger than cache capacity %d", key, size, s.maxBytes)
+	}
+
+	path := s.path(key)
+	tmp, err := writeTempFile(path, key, value, expiresAt)
+	if err != nil {
+		return err
+	}
+	if err = s.commit(key, tmp, path, size, expiresAt, stale); err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func (s *diskStore) commit(key, tmp, path string, size int64, expiresAt time.Time, stale func() bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return constant.ErrClosed
+	}
+	if stale != nil && stale() {
+		return errStale
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if existing, exists := s.items[key]; exists {
+		s.unlink(existing)
+	}
+
+	entry := &diskEntry{key: key, expiresAt: expiresAt, size: size}
+	s.items[key] = entry
+	s.addToHead(entry)
+	s.usedBytes += size
+
+	return s.evict(0)
+}
+
+func (s *diskStore) delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exists := s.items[key]; exists {
+		return s.removeEntry(entry)
+	}
+	return nil
+}
+
+// clear removes every entry whose key matches.
+func (s *diskStore) clear(match func(key string) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for key, entry := range s.items {
+		if !match(key) {
+			continue
+		}
+		if err := s.removeEntry(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *diskStore) sweepExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	swept := 0
+	for _, entry := range s.items {
+		if entry.isExpired(now) {
+			_ = s.removeEntry(entry)
+			swept++
+		}
+	}
+	return swept
+}
+
+func (s *diskStore) liveKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(s.items))
+	for entry := s.head.next; entry != s.tail; entry = entry.next {
+		if !entry.isExpired(now) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+// close refuses further writes and syncs the cache directories, so that the
+// renames of entries written before it survive a crash.
+func (s *diskStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to sync cache directory: %w", err)
+	}
+	errs := []error{syncDir(s.dir)}
+	for _, dir := range dirs {
+		if dir.IsDir() && isHashedName(dir.Name(), 2) {
+			errs = append(errs, syncDir(filepath.Join(s.dir, dir.Name())))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to sync cache directory: %w", err)
+	}
+	defer dir.Close()
+	if err = dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync cache directory: %w", err)
+	}
+	return nil
+}
+
+func (s *diskStore) size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usedBytes
+}
+
+func (s *diskStore) evict(incoming int64) error {
+	for s.usedBytes+incoming > s.maxBytes {
+		last := s.tail.prev
+		if last == s.head {
+			return nil
+		}
+		if err := s.removeEntry(last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *diskStore) removeEntry(entry *diskEntry) error {
+	s.unlink(entry)
+	if err := os.Remove(s.path(entry.key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cache file: %w", err)
+	}
+	return nil
+}
+
+func (s *diskStore) unlink(entry *diskEntry) {
+	entry.prev.next = entry.next
+	entry.next.prev = entry.prev
+	delete(s.items, entry.key)
+	s.usedBytes -= entry.size
+}
+
+func (s *diskStore) addToHead(entry *diskEntry) {
+	entry.prev = s.head
+	entry.next = s.head.next
+	s.head.next.prev = entry
+	s.head.next = entry
+}
+
+func (s *diskStore) moveToHead(entry *diskEntry) {
+	entry.prev.next = entry.next
+	entry.next.prev = entry.prev
+	s.addToHead(entry)
+}
+
+func (s *diskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, name[:2], name[2:])
+}
+
+// writeTempFile writes an entry file next to path and syncs it, returning
+// the temporary file's name for the caller to rename into place.
+func writeTempFile(path, key string, value []byte, expiresAt time.Time) (string, error) {
+	if len(key) > maxKeyLength {
+		return "", fmt.Errorf("key length %d exceeds the maximum of %d", len(key), maxKeyLength)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), tmpFilePrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+
+	var expires int64
+	if !expiresAt.IsZero() {
+		expires = expiresAt.UnixNano()
+	}
+
+	header := make([]byte, fileHeaderSize, fileHeaderSize+len(key))
+	copy(header, fileMagic)
+	header[len(fileMagic)] = fileVersion
+	binary.BigEndian.PutUint64(header[len(fileMagic)+1:], uint64(expires))
+	binary.BigEndian.PutUint32(header[len(fileMagic)+9:], uint32(len(key)))
+	header = append(header, key...)
+
+	if _, err = tmp.Write(header); err == nil {
+		_, err = tmp.Write(value)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+func readFileHeader(path string) (string, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+
+	header := make([]byte, fileHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if n < len(fileMagic) || string(header[:len(fileMagic)]) != fileMagic {
+		return "", time.Time{}, errNotCacheFile
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if header[len(fileMagic)] != fileVersion {
+		return "", time.Time{}, fmt.Errorf("unsupported cache file version %d", header[len(fileMagic)])
+	}
+
+	var expiresAt time.Time
+	if expires := int64(binary.BigEndian.Uint64(header[len(fileMagic)+1:])); expires != 0 {
+		expiresAt = time.Unix(0, expires)
+	}
+
+	keyLen := binary.BigEndian.Uint32(header[len(fileMagic)+9:])
+	if keyLen > maxKeyLength {
+		return "", time.Time{}, fmt.Errorf("cache file key length %d exceeds the maximum of %d", keyLen, maxKeyLength)
+	}
+	key := make([]byte, keyLen)
+	if _, err = io.ReadFull(f, key); err != nil {
+		return "", time.Time{}, err
+	}
+	return string(key), expiresAt, nil
+}
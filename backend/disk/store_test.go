@@ -0,0 +1,229 @@
+package disk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+func TestDiskStoreSetAndGet(t *testing.T) {
+	store, err := openDiskStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = store.set("key1", []byte("value1"), time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, found, err := store.get("key1")
+	if err != nil || !found {
+		t.Fatalf("expected key1, got found=%v err=%v", found, err)
+	}
+	if string(data) != "value1" {
+		t.Errorf("expected 'value1', got '%s'", data)
+	}
+
+	if _, found, _ = store.get("missing"); found {
+		t.Error("expected missing key to not be found")
+	}
+}
+
+func TestDiskStoreExpiredEntry(t *testing.T) {
+	store, err := openDiskStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = store.set("key1", []byte("value1"), time.Now().Add(-time.Second))
+	_ = store.set("key2", []byte("value2"), time.Now().Add(time.Hour))
+
+	if _, found, _ := store.get("key1"); found {
+		t.Error("expected expired key to not be found")
+	}
+	if _, err = os.Stat(store.path("key1")); !os.IsNotExist(err) {
+		t.Error("expected expired file to be removed")
+	}
+
+	_ = store.set("key3", []byte("value3"), time.Now().Add(-time.Second))
+	if swept := store.sweepExpired(); swept != 1 {
+		t.Errorf("expected 1 swept entry, got %d", swept)
+	}
+	if keys := store.liveKeys(); len(keys) != 1 || keys[0] != "key2" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestDiskStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	value := make([]byte, 100)
+	entrySize := int64(fileHeaderSize + len("keyN") + len(value))
+
+	store, err := openDiskStore(t.TempDir(), 3*entrySize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = store.set("key1", value, time.Time{})
+	_ = store.set("key2", value, time.Time{})
+	_ = store.set("key3", value, time.Time{})
+
+	_, _, _ = store.get("key1")
+
+	_ = store.set("key4", value, time.Time{})
+
+	if _, found, _ := store.get("key2"); found {
+		t.Error("expected key2 to be evicted")
+	}
+	for _, key := range []string{"key1", "key3", "key4"} {
+		if _, found, _ := store.get(key); !found {
+			t.Errorf("expected %s to remain", key)
+		}
+	}
+	if store.size() > 3*entrySize {
+		t.Errorf("expected used bytes within capacity, got %d", store.size())
+	}
+}
+
+func TestDiskStoreRejectsOversizedEntry(t *testing.T) {
+	store, err := openDiskStore(t.TempDir(), 64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = store.set("key1", make([]byte, 128), time.Time{}); err == nil {
+		t.Error("expected error for entry larger than capacity")
+	}
+}
+
+func TestDiskStoreReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = store.set("key1", []byte("value1"), time.Time{})
+	_ = store.set("key2", []byte("value2"), time.Now().Add(time.Hour))
+	_ = store.set("expired", []byte("value"), time.Now().Add(-time.Second))
+	if err = os.WriteFile(filepath.Join(dir, "garbage"), []byte("nope"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reopened.liveKeys()) != 2 {
+		t.Errorf("expected 2 keys after reopen, got %v", reopened.liveKeys())
+	}
+	data, found, err := reopened.get("key2")
+	if err != nil || !found || string(data) != "value2" {
+		t.Errorf("expected key2 after reopen, got %q found=%v err=%v", data, found, err)
+	}
+	if reopened.size() != store.size()-int64(fileHeaderSize+len("expired")+len("value")) {
+		t.Errorf("unexpected used bytes after reopen: %d", reopened.size())
+	}
+	if _, err = os.Stat(filepath.Join(dir, "garbage")); err != nil {
+		t.Errorf("expected a file the store did not write to be kept, got %v", err)
+	}
+}
+
+func TestDiskStoreLoadOnlyRemovesCacheFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = store.set("key1", []byte("value1"), time.Time{})
+	hashed := filepath.Dir(store.path("key1"))
+
+	corrupt := make([]byte, fileHeaderSize)
+	copy(corrupt, fileMagic)
+	corrupt[len(fileMagic)] = fileVersion
+	binary.BigEndian.PutUint32(corrupt[len(fileMagic)+9:], 1<<31)
+	files := map[string][]byte{
+		filepath.Join(dir, "notes.txt"):                             []byte("keep me"),
+		filepath.Join(dir, "project", "main.go"):                    []byte("package main"),
+		filepath.Join(hashed, tmpFilePrefix+"user"):                 []byte("user file"),
+		filepath.Join(hashed, strings.Repeat("0", sha256.Size*2-2)): []byte("no magic"),
+		filepath.Join(hashed, strings.Repeat("1", sha256.Size*2-2)): corrupt,
+		filepath.Join(hashed, tmpFilePrefix+"partial"):              []byte(fileMagic + "partial"),
+	}
+	for path, data := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err = os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	reopened, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys := reopened.liveKeys(); len(keys) != 1 || keys[0] != "key1" {
+		t.Errorf("expected only key1 after reopen, got %v", keys)
+	}
+	for path, data := range files {
+		_, err := os.Stat(path)
+		ours := bytes.HasPrefix(data, []byte(fileMagic))
+		if ours && !os.IsNotExist(err) {
+			t.Errorf("expected corrupt cache file %s to be removed", path)
+		}
+		if !ours && err != nil {
+			t.Errorf("expected foreign file %s to be kept, got %v", path, err)
+		}
+	}
+}
+
+func TestDiskStoreClear(t *testing.T) {
+	store, err := openDiskStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = store.set("key1", []byte("value1"), time.Time{})
+	_ = store.set("key2", []byte("value2"), time.Time{})
+
+	if err = store.clear(func(string) bool { return true }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.liveKeys()) != 0 || store.size() != 0 {
+		t.Error("expected empty store after clear")
+	}
+}
+
+func TestDiskStoreCloseRefusesWrites(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = store.set("key", []byte("value"), time.Time{})
+
+	if err = store.close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = store.set("other", []byte("value"), time.Time{}); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if data, found, err := store.get("key"); err != nil || !found || string(data) != "value" {
+		t.Errorf("expected reads to keep working, got %q found=%v err=%v", data, found, err)
+	}
+
+	reopened, err := openDiskStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys := reopened.liveKeys(); len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("expected only the write before close on disk, got %v", keys)
+	}
+}
@@ -0,0 +1,227 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/remote"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dataSegment = "data:"
+	lockSegment = "lock:"
+	scanCount   = 1000
+)
+
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func init() {
+	if err := remote.RegisterTypedStore("redis", NewRedisStore); err != nil {
+		panic(err)
+	}
+}
+
+type RedisConfig struct {
+	Address     string        `json:"address" required:"true"`
+	Password    string        `json:"password,omitempty"`
+	KeyPrefix   string        `json:"keyPrefix,omitempty"`
+	DB          int           `json:"db"`
+	PoolSize    int           `json:"poolSize,omitempty"`
+	MaxRetries  int           `json:"maxRetries,omitempty"`
+	DialTimeout time.Duration `json:"dialTimeout,omitempty"`
+}
+
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(cfg RedisConfig) (remote.Store, error) {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:        cfg.Address,
+		Password:    cfg.Password,
+		DB:          cfg.DB,
+		PoolSize:    cfg.PoolSize,
+		MaxRetries:  cfg.MaxRetries,
+		DialTimeout: cfg.DialTimeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+	defer cancel()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return NewRedisStoreWithClient(rdb, cfg.KeyPrefix), nil
+}
+
+func NewRedisStoreWithClient(client redis.UniversalClient, keyPrefix string) *RedisStore {
+	if keyPrefix == "" {
+		keyPrefix = "invacache:"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: keyPrefix,
+	}
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := r.client.Get(ctx, r.dataKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (r *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.dataKey(key), value, ttl).Err()
+}
+
+func (r *RedisStore) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.dataKey(key)).Err()
+}
+
+func (r *RedisStore) Clear(ctx context.Context) error {
+	return r.ClearPrefix(ctx, "")
+}
+
+// ClearPrefix unlinks the keys starting with prefix, scanning only the
+// matching part of the keyspace.
+func (r *RedisStore) ClearPrefix(ctx context.Context, prefix string) error {
+	return r.ClearBatches(ctx, prefix, 0)
+}
+
+// ClearBatches is ClearPrefix with each SCAN and UNLINK round bounded by
+// batchTimeout instead of the whole clear; zero leaves only ctx.
+func (r *RedisStore) ClearBatches(ctx context.Context, prefix string, batchTimeout time.Duration) error {
+	return r.scan(ctx, prefix, batchTimeout, func(ctx context.Context, keys []string) error {
+		return r.client.Unlink(ctx, keys...).Err()
+	})
+}
+
+// Keys lists the data keys once each, although SCAN may return a key more
+// than once.
+func (r *RedisStore) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	seen := make(map[string]struct{})
+	dataPrefix := r.prefix + dataSegment
+	err := r.scan(ctx, "", 0, func(_ context.Context, batch []string) error {
+		for _, key := range batch {
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, strings.TrimPrefix(key, dataPrefix))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (r *RedisStore) TryLock(ctx context.Context, key string, ttl time.Duration) (remote.UnlockFunc, bool, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	lockKey := r.prefix + lockSegment + key
+	acquired, err := r.client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return func(ctx context.Context) error {
+		return unlockScript.Run(ctx, r.client, []string{lockKey}, token).Err()
+	}, true, nil
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisStore) dataKey(key string) string {
+	return r.prefix + dataSegment + key
+}
+
+// scan calls fn with batches of the data keys starting with prefix. With a
+// batchTimeout, each SCAN and the fn call after it share a context bounded by
+// it.
+func (r *RedisStore) scan(ctx context.Context, prefix string, batchTimeout time.Duration, fn func(ctx context.Context, keys []string) error) error {
+	match := escapePattern(r.dataKey(prefix)) + "*"
+	var cursor uint64
+	for {
+		next, err := r.scanBatch(ctx, cursor, match, batchTimeout, fn)
+		if err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (r *RedisStore) scanBatch(ctx context.Context, cursor uint64, match string, batchTimeout time.Duration, fn func(ctx context.Context, keys []string) error) (uint64, error) {
+	if batchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, batchTimeout)
+		defer cancel()
+	}
+
+	keys, next, err := r.client.Scan(ctx, cursor, match, scanCount).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) > 0 {
+		if err = fn(ctx, keys); err != nil {
+			return 0, err
+		}
+	}
+	return next, nil
+}
+
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func newLockToken() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
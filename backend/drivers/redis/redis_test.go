@@ -0,0 +1,219 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/halilbulentorhon/invacache-go/backend/remote"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T, prefix string) (*RedisStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	store := NewRedisStoreWithClient(redis.NewClient(&redis.Options{Addr: server.Addr()}), prefix)
+	t.Cleanup(func() { _ = store.Close() })
+	return store, server
+}
+
+func TestClearOnlyTouchesOwnPrefix(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t, "app:")
+
+	_ = store.Set(ctx, "user:1", []byte("v"), 0)
+	_ = store.Set(ctx, "user:2", []byte("v"), 0)
+	foreign := []string{"other:data:user:1", "app-other:data:user:1", "unrelated"}
+	for _, key := range foreign {
+		_ = server.Set(key, "keep")
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys, _ := store.Keys(ctx); len(keys) != 0 {
+		t.Errorf("expected the store to be empty, got %v", keys)
+	}
+	for _, key := range foreign {
+		if !server.Exists(key) {
+			t.Errorf("expected foreign key %s to survive Clear", key)
+		}
+	}
+}
+
+func TestKeysTrimsPrefix(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t, "app:")
+
+	_ = store.Set(ctx, "user:1", []byte("v"), 0)
+	_ = store.Set(ctx, "order:1", []byte("v"), time.Minute)
+	_ = server.Set("other:data:user:2", "v")
+	unlock, _, _ := store.TryLock(ctx, "user:1", time.Minute)
+	defer func() { _ = unlock(ctx) }()
+
+	keys, err := store.Keys(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "order:1" || keys[1] != "user:1" {
+		t.Errorf("expected order:1 and user:1 without prefix, got %v", keys)
+	}
+}
+
+func TestTryLockAndTokenCheckedUnlock(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t, "app:")
+
+	unlock, acquired, err := store.TryLock(ctx, "user:1", time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("expected to acquire the lock, got %v, %v", acquired, err)
+	}
+	if _, acquired, _ = store.TryLock(ctx, "user:1", time.Second); acquired {
+		t.Error("expected a held lock not to be acquired twice")
+	}
+	if err = unlock(ctx); err != nil {
+		t.Fatalf("unexpected unlock error: %v", err)
+	}
+	if server.Exists("app:lock:user:1") {
+		t.Error("expected unlock to release the lock")
+	}
+
+	expired, _, _ := store.TryLock(ctx, "user:1", time.Second)
+	server.FastForward(2 * time.Second)
+	_, acquired, _ = store.TryLock(ctx, "user:1", time.Minute)
+	if !acquired {
+		t.Fatal("expected an expired lock to be taken over")
+	}
+	if err = expired(ctx); err != nil {
+		t.Fatalf("unexpected unlock error: %v", err)
+	}
+	if !server.Exists("app:lock:user:1") {
+		t.Error("expected a stale unlock not to release the new owner's lock")
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	if got := escapePattern(`a*b?[c]\d`); got != `a\*b\?\[c\]\\d` {
+		t.Errorf("unexpected escaped pattern %q", got)
+	}
+
+	ctx := context.Background()
+	store, server := newTestStore(t, "app[1]*:")
+	_ = store.Set(ctx, "user:1", []byte("v"), 0)
+	for _, key := range []string{"app1x:data:user:1", "app1:data:user:1", "app[1]:data:user:1"} {
+		_ = server.Set(key, "keep")
+	}
+
+	keys, err := store.Keys(ctx)
+	if err != nil || len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("expected only the store's own key, got %v, %v", keys, err)
+	}
+	_ = store.Clear(ctx)
+	for _, key := range []string{"app1x:data:user:1", "app1:data:user:1", "app[1]:data:user:1"} {
+		if !server.Exists(key) {
+			t.Errorf("expected %s not to match the escaped prefix", key)
+		}
+	}
+}
+
+func TestClearPrefixOnlyRemovesMatchingKeys(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t, "app:")
+
+	for _, key := range []string{"user:1", "user:2", "username", "user*:1", "order:1"} {
+		_ = store.Set(ctx, key, []byte("v"), 0)
+	}
+	if err := store.ClearPrefix(ctx, "user:"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys, _ := store.Keys(ctx)
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "order:1" || keys[1] != "user*:1" || keys[2] != "username" {
+		t.Errorf("expected only user:1 and user:2 to be removed, got %v", keys)
+	}
+
+	if err := store.ClearPrefix(ctx, "user*"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys, _ = store.Keys(ctx); len(keys) != 2 {
+		t.Errorf("expected the glob character in the prefix to match literally, got %v", keys)
+	}
+}
+
+// scanHook repeats every key SCAN returns, as SCAN may during a rehash, and
+// records whether each SCAN had a deadline.
+type scanHook struct {
+	deadlines []bool
+}
+
+func (h *scanHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *scanHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if scan, ok := cmd.(*redis.ScanCmd); ok && err == nil {
+			_, hasDeadline := ctx.Deadline()
+			h.deadlines = append(h.deadlines, hasDeadline)
+			keys, cursor := scan.Val()
+			scan.SetVal(append(keys, keys...), cursor)
+		}
+		return err
+	}
+}
+
+func (h *scanHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestKeysDeduplicatesScanResults(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t, "app:")
+	store.client.AddHook(&scanHook{})
+
+	_ = store.Set(ctx, "user:1", []byte("v"), 0)
+	_ = store.Set(ctx, "user:2", []byte("v"), 0)
+
+	keys, err := store.Keys(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Errorf("expected each key once, got %v", keys)
+	}
+}
+
+func TestClearBatchesBoundsEachBatch(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t, "app:")
+	for i := 0; i < 2*scanCount+1; i++ {
+		_ = store.Set(ctx, fmt.Sprintf("user:%d", i), []byte("v"), 0)
+	}
+	hook := &scanHook{}
+	store.client.AddHook(hook)
+
+	// miniredis scans by offset, so keys unlinked mid-scan make it skip others;
+	// only the batching is checked here.
+	if err := store.ClearBatches(ctx, "user:", time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hook.deadlines) < 2 {
+		t.Fatalf("expected several SCAN batches, got %d", len(hook.deadlines))
+	}
+	for idx, hasDeadline := range hook.deadlines {
+		if !hasDeadline {
+			t.Errorf("expected SCAN batch %d to have its own deadline", idx)
+		}
+	}
+}
+
+func TestNewStoreRequiresAddress(t *testing.T) {
+	_, err := remote.NewStore("redis", map[string]any{"keyPrefix": "app:"})
+	if err == nil || !strings.Contains(err.Error(), "address") {
+		t.Errorf("expected a missing address to be rejected, got %v", err)
+	}
+}
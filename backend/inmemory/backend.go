@@ -3,62 +3,115 @@ package inmemory
 import (
 	"context"
 	"fmt"
-	"hash/fnv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/halilbulentorhon/invacache-go/backend"
 	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
 	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/codec"
 	"github.com/halilbulentorhon/invacache-go/config"
 	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
 	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+	"github.com/halilbulentorhon/invacache-go/pkg/mempressure"
+	"github.com/halilbulentorhon/invacache-go/pkg/singleflight"
+)
+
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
 )
 
 type inMemoryBackend[V any] struct {
-	ctx          context.Context
-	invalidator  invalidation.PubSub
-	logger       logger.Logger
-	cancel       context.CancelFunc
-	singleFlight singleFlight[V]
-	shards       []inMemoryShard[V]
+	ctx             context.Context
+	invalidator     *invalidation.Coordinator
+	delivery        option.DeliveryDefaults
+	logger          logger.Logger
+	codec           codec.Codec[V]
+	clock           clock.Clock
+	pressure        *pressureWatcher
+	cancel          context.CancelFunc
+	table           atomic.Pointer[shardTable[V]]
+	singleFlight    singleflight.Group[V]
+	snapshotPath    string
+	sweeperInterval time.Duration
+	closeErr        error
+	wg              sync.WaitGroup
+	resizeMu        sync.RWMutex
+	closeOnce       sync.Once
+	closed          atomic.Bool
+	// epoch counts clears. It starts at 1 so that a zero option.WithEpoch
+	// means an untagged write.
+	epoch atomic.Uint64
 }
 
 func (i *inMemoryBackend[V]) Clear(options ...option.ClrOptFnc) error {
-	for idx := range i.shards {
-		i.shards[idx].mu.Lock()
-		i.shards[idx].clear()
-		i.shards[idx].mu.Unlock()
+	if i.closed.Load() {
+		return constant.ErrClosed
 	}
 
 	cfg := option.ApplyClearOptions(options)
+	message := invalidation.Message{
+		Namespace: cfg.Namespace,
+		Stamp:     i.invalidator.Stamp(),
+		Epoch:     i.epoch.Add(1),
+	}
+	i.clear(cfg)
+	return i.publishInvalidation(message, cfg.Delivery.Or(i.delivery.Clear))
+}
 
-	if cfg.PublishInvalidation {
-		if pubErr := i.publishInvalidation(constant.EmptyString); pubErr != nil {
-			i.logger.Warn("failed to publish invalidation for clear event", "error", pubErr)
+// clear removes the entries cfg covers. The epoch must already have been
+// advanced, so that loads that started earlier are not stored afterwards.
+func (i *inMemoryBackend[V]) clear(cfg option.ClearConfig) {
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.Lock()
+		if cfg.Namespace == "" {
+			shards[idx].clear()
+		} else {
+			shards[idx].deleteMatching(cfg.Matches)
 		}
+		shards[idx].mu.Unlock()
 	}
+}
 
-	return nil
+// Epoch returns the current clear epoch; see backend.EpochReporter.
+func (i *inMemoryBackend[V]) Epoch() uint64 {
+	return i.epoch.Load()
+}
+
+// Stamp returns a write stamp; see backend.Stamper.
+func (i *inMemoryBackend[V]) Stamp() int64 {
+	return i.invalidator.Stamp()
 }
 
 func (i *inMemoryBackend[V]) Get(key string) (V, error) {
-	shard := i.getShard(key)
-	shard.mu.Lock()
+	shard := i.lockShard(key)
 	defer shard.mu.Unlock()
 
 	return shard.get(key)
 }
 
 func (i *inMemoryBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V]) (V, error) {
-	shard := i.getShard(key)
-
-	shard.mu.Lock()
+	shard := i.lockShard(key)
 	if value, err := shard.get(key); err == nil {
 		shard.mu.Unlock()
 		return value, nil
 	}
 	shard.mu.Unlock()
+	if i.closed.Load() {
+		var zero V
+		return zero, constant.ErrClosed
+	}
 
+	// The stamp and epoch are taken before loading: the loaded value may
+	// predate any write or clear that happens while the loader runs.
+	stamp := i.invalidator.Stamp()
+	epoch := i.epoch.Load()
 	value, ttl, err := i.singleFlight.Do(key, func() (V, time.Duration, error) {
 		return loader(key)
 	})
@@ -67,77 +120,180 @@ func (i *inMemoryBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V])
 		return zero, err
 	}
 
-	shard.mu.Lock()
+	shard = i.lockShard(key)
 	defer shard.mu.Unlock()
 
 	if existing, err := shard.get(key); err == nil {
 		return existing, nil
 	}
+	if i.epoch.Load() != epoch {
+		return value, nil
+	}
 
 	if setErr := shard.set(key, value, option.WithTTL(ttl)); setErr != nil {
 		var zero V
 		return zero, setErr
 	}
+	if stamp != 0 {
+		shard.stampWrite(key, stamp)
+	}
 	return value, nil
 }
 
 func (i *inMemoryBackend[V]) Set(key string, value V, options ...option.OptFnc) error {
-	shard := i.getShard(key)
-	shard.mu.Lock()
+	if i.closed.Load() {
+		return constant.ErrClosed
+	}
+
+	cfg := setConfig(options)
+	stamp := cfg.Stamp
+	if stamp == 0 {
+		stamp = i.invalidator.Stamp()
+	}
+	shard := i.lockShard(key)
+	if cfg.Epoch != 0 && cfg.Epoch < i.epoch.Load() {
+		shard.mu.Unlock()
+		return fmt.Errorf("%w: %s", constant.ErrStaleEpoch, key)
+	}
 	err := shard.set(key, value, options...)
+	if err == nil && stamp != 0 {
+		shard.stampWrite(key, stamp)
+	}
 	shard.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	cfg := option.ApplyOptions(options)
-	if cfg.PublishInvalidation {
-		if pubErr := i.publishInvalidation(key); pubErr != nil {
-			i.logger.Warn("failed to publish invalidation", "key", key, "error", pubErr)
-		}
+	return i.publishInvalidation(invalidation.Message{Key: key, Stamp: stamp}, cfg.Delivery.Or(i.delivery.Set))
+}
+
+func (i *inMemoryBackend[V]) Health() backend.Health {
+	return backend.Health{
+		Backend:      constant.InMemoryBackend,
+		Closed:       i.closed.Load(),
+		Invalidation: i.invalidator.Health(),
 	}
+}
 
-	return nil
+func (i *inMemoryBackend[V]) WaitReady(ctx context.Context) error {
+	return i.invalidator.WaitReady(ctx)
 }
 
-func (i *inMemoryBackend[V]) publishInvalidation(key string) error {
-	if i.invalidator != nil {
-		err := i.invalidator.Publish(i.ctx, key)
-		if err != nil {
-			return fmt.Errorf("failed to publish invalidation for key %s: %w", key, err)
-		}
+func (i *inMemoryBackend[V]) Flush(ctx context.Context) error {
+	return i.invalidator.Flush(ctx)
+}
+
+func (i *inMemoryBackend[V]) InvalidationState() invalidation.ConnectionState {
+	return i.invalidator.State()
+}
+
+func (i *inMemoryBackend[V]) publishInvalidation(message invalidation.Message, delivery option.Delivery) error {
+	if i.invalidator == nil {
+		return nil
+	}
+	switch delivery {
+	case option.DeliveryAsync:
+		return i.invalidator.Publish(message)
+	case option.DeliverySync:
+		return i.invalidator.PublishSync(message)
+	default:
+		return nil
 	}
-	return nil
+}
+
+// deleteIfOlder applies a peer's stamped invalidation, keeping the entry if
+// its last write is not older than stamp.
+func (i *inMemoryBackend[V]) deleteIfOlder(key string, stamp int64) (bool, error) {
+	if i.closed.Load() {
+		return false, constant.ErrClosed
+	}
+	shard := i.lockShard(key)
+	defer shard.mu.Unlock()
+	return shard.deleteIfOlder(key, stamp), nil
 }
 
 func (i *inMemoryBackend[V]) Delete(key string, options ...option.DelOptFnc) error {
-	shard := i.getShard(key)
-	shard.mu.Lock()
+	if i.closed.Load() {
+		return constant.ErrClosed
+	}
+
+	stamp := i.invalidator.Stamp()
+	shard := i.lockShard(key)
 	err := shard.delete(key)
 	shard.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	cfg := option.ApplyDeleteOptions(options)
-	if cfg.PublishInvalidation {
-		if pubErr := i.publishInvalidation(key); pubErr != nil {
-			i.logger.Warn("failed to publish invalidation", "key", key, "error", pubErr)
+	cfg := deleteConfig(options)
+	return i.publishInvalidation(invalidation.Message{Key: key, Stamp: stamp}, cfg.Delivery.Or(i.delivery.Delete))
+}
+
+// Len counts the live entries. It waits for a Reshard in progress, whose
+// migrated entries are only reachable through the new table until it ends.
+func (i *inMemoryBackend[V]) Len() int {
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+	shards := i.table.Load().shards
+	total := 0
+	for idx := range shards {
+		shards[idx].mu.RLock()
+		total += shards[idx].liveCount()
+		shards[idx].mu.RUnlock()
+	}
+	return total
+}
+
+func (i *inMemoryBackend[V]) Keys() []string {
+	keys := make([]string, 0, i.Len())
+	i.Range(func(key string, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range copies one shard at a time, waiting for a Reshard in progress. A
+// Reshard that completes between two shards moves entries within the walk,
+// so some may then be skipped or visited twice.
+func (i *inMemoryBackend[V]) Range(fn func(key string, value V) bool) {
+	for idx := 0; ; idx++ {
+		items, ok := i.shardItems(idx)
+		if !ok {
+			return
+		}
+		for _, item := range items {
+			if !fn(item.key, item.value) {
+				return
+			}
 		}
 	}
+}
 
-	return nil
+// shardItems copies the live entries of shard idx, reporting false past the
+// last shard. It is called without holding resizeMu, so fn in Range may call
+// back into the cache.
+func (i *inMemoryBackend[V]) shardItems(idx int) ([]shardItem[V], bool) {
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+	shards := i.table.Load().shards
+	if idx >= len(shards) {
+		return nil, false
+	}
+	shards[idx].mu.RLock()
+	defer shards[idx].mu.RUnlock()
+	return shards[idx].liveItems(), true
 }
 
-func (i *inMemoryBackend[V]) runSweeper(shard *inMemoryShard[V], interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (i *inMemoryBackend[V]) runSweeper(ctx context.Context, shard *inMemoryShard[V], ticker clock.Ticker) {
+	defer i.wg.Done()
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-i.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			shard.mu.Lock()
 			shard.sweepExpired()
 			shard.mu.Unlock()
@@ -146,25 +302,113 @@ func (i *inMemoryBackend[V]) runSweeper(shard *inMemoryShard[V], interval time.D
 }
 
 func (i *inMemoryBackend[V]) Close() error {
+	return i.CloseContext(context.Background())
+}
+
+// CloseContext stops accepting writes, snapshots the cache if configured,
+// flushes queued invalidations, cancels the sweepers, the memory-pressure watcher and the invalidation
+// subscription and waits for them to exit before closing the invalidation
+// driver. Without a deadline on ctx, the flush gives up after
+// constant.DefaultCloseFlushTimeout. If ctx ends first the driver is still closed and ctx's error is
+// returned. Later calls return the result of the first one.
+func (i *inMemoryBackend[V]) CloseContext(ctx context.Context) error {
+	i.closeOnce.Do(func() {
+		i.closeErr = i.shutdown(ctx)
+	})
+	return i.closeErr
+}
+
+func (i *inMemoryBackend[V]) shutdown(ctx context.Context) error {
 	i.logger.Info("closing inmemory cache")
+	i.resizeMu.Lock()
+	i.closed.Store(true)
+	i.resizeMu.Unlock()
+
+	if i.snapshotPath != "" {
+		if err := i.snapshotToFile(i.snapshotPath); err != nil {
+			i.logger.Error("failed to snapshot cache", "path", i.snapshotPath, "error", err)
+		}
+	}
+
+	if err := i.invalidator.FlushOnClose(ctx); err != nil {
+		i.logger.Warn("pending invalidations were not published", "error", err)
+	}
+
+	i.cancel()
+	waitErr := i.waitForBackground(ctx)
+	if waitErr != nil {
+		i.logger.Warn("background goroutines did not stop in time", "error", waitErr)
+	}
+
 	if i.invalidator != nil {
-		err := i.invalidator.Close()
-		if err != nil {
+		if err := i.invalidator.Close(); err != nil {
 			i.logger.Error("failed to close invalidator", "error", err)
 			return err
 		}
 	}
-	if i.cancel != nil {
-		i.cancel()
+	if waitErr != nil {
+		return waitErr
 	}
 	i.logger.Info("inmemory cache closed successfully")
 	return nil
 }
 
-func (i *inMemoryBackend[V]) getShard(key string) *inMemoryShard[V] {
-	h := fnv.New32a()
-	h.Write([]byte(key))
-	return &i.shards[h.Sum32()%uint32(len(i.shards))]
+func (i *inMemoryBackend[V]) waitForBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		i.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lockShard returns the locked shard that owns key, following a reshard in
+// progress to the table the key has already been migrated to.
+func (i *inMemoryBackend[V]) lockShard(key string) *inMemoryShard[V] {
+	hash := hashKey(key)
+	table := i.table.Load()
+	for {
+		shard := &table.shards[hash&table.mask]
+		shard.mu.Lock()
+		if !shard.migrated {
+			return shard
+		}
+		shard.mu.Unlock()
+		table = table.next
+	}
+}
+
+func hashKey(key string) uint32 {
+	hash := uint32(fnvOffset32)
+	for idx := 0; idx < len(key); idx++ {
+		hash ^= uint32(key[idx])
+		hash *= fnvPrime32
+	}
+	return hash
+}
+
+// shardCountFor rounds requested up to a power of two, or down when rounding
+// up would leave no more entries than shards.
+func shardCountFor(requested, capacity int) int {
+	count := nextPowerOfTwo(requested)
+	if count >= capacity && count > 1 {
+		count >>= 1
+	}
+	return count
+}
+
+func nextPowerOfTwo(n int) int {
+	power := 1
+	for power < n {
+		power <<= 1
+	}
+	return power
 }
 
 func getInvalidatorConfig(cfg *config.InvalidationConfig) interface{} {
@@ -174,73 +418,144 @@ func getInvalidatorConfig(cfg *config.InvalidationConfig) interface{} {
 	case constant.RedisInvalidationConfigType:
 		return cfg.DriverConfig
 	default:
+		if invalidation.IsRegistered(cfg.Type) {
+			return cfg.DriverConfig
+		}
 		return nil
 	}
 }
 
 func (i *inMemoryBackend[V]) handleInvalidationMessage(key string) error {
 	if isClearEvent(key) {
-		return i.Clear()
+		return i.Clear(option.WithClearDelivery(option.DeliveryLocal))
 	}
 
-	return i.Delete(key)
+	return i.Delete(key, option.WithDeleteDelivery(option.DeliveryLocal))
+}
+
+// handleClearMessage applies a peer's clear. The local epoch moves past both
+// its own value and the peer's, so loads in flight here are refused too.
+func (i *inMemoryBackend[V]) handleClearMessage(namespace string, epoch uint64) error {
+	if i.closed.Load() {
+		return constant.ErrClosed
+	}
+	for {
+		current := i.epoch.Load()
+		if i.epoch.CompareAndSwap(current, max(current+1, epoch)) {
+			break
+		}
+	}
+	i.clear(option.ClearConfig{Namespace: namespace})
+	return nil
+}
+
+// setConfig and deleteConfig skip the option package for calls without
+// options: handing the config to the option functions moves it to the heap,
+// and the defaults are the zero config.
+func setConfig(options []option.OptFnc) option.SetConfig {
+	if len(options) == 0 {
+		return option.SetConfig{}
+	}
+	return option.ApplyOptions(options)
+}
+
+func deleteConfig(options []option.DelOptFnc) option.DeleteConfig {
+	if len(options) == 0 {
+		return option.DeleteConfig{}
+	}
+	return option.ApplyDeleteOptions(options)
 }
 
 func isClearEvent(key string) bool {
-	return key == constant.EmptyString
+	return invalidation.IsClearEvent(key)
 }
 
 func NewInMemoryBackend[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
-	log := logger.NewLogger("inmemory-cache")
+	log := cfg.NewLogger("inmemory-cache")
 	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
+	shardCount := shardCountFor(cfg.Backend.InMemory.ShardCount, cfg.Backend.InMemory.Capacity)
+	if shardCount != cfg.Backend.InMemory.ShardCount {
+		log.Warn("shard count rounded to a power of two", "requested", cfg.Backend.InMemory.ShardCount, "shard_count", shardCount)
+	}
 	log.Info("initializing inmemory cache",
-		"shard_count", cfg.Backend.InMemory.ShardCount,
+		"shard_count", shardCount,
 		"capacity", cfg.Backend.InMemory.Capacity,
 		"sweeper_interval", cfg.Backend.InMemory.SweeperInterval)
 
-	shards := make([]inMemoryShard[V], cfg.Backend.InMemory.ShardCount)
-	baseCapacity := cfg.Backend.InMemory.Capacity / cfg.Backend.InMemory.ShardCount
-	remainder := cfg.Backend.InMemory.Capacity % cfg.Backend.InMemory.ShardCount
-	for i := range shards {
-		capacity := baseCapacity
-		if i == len(shards)-1 && remainder > 0 {
-			capacity += remainder
-		}
-		shards[i] = newInMemoryShard[V](capacity, cfg.Backend.InMemory.DefaultTTL)
+	valueCodec, err := codec.New[V](cfg.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec: %w", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	be := &inMemoryBackend[V]{
-		shards:       shards,
-		singleFlight: singleFlight[V]{},
-		ctx:          ctx,
-		cancel:       cancel,
-		logger:       log,
+		singleFlight:    singleflight.Group[V]{},
+		ctx:             ctx,
+		cancel:          cancel,
+		logger:          log,
+		codec:           valueCodec,
+		clock:           cfg.Clock,
+		snapshotPath:    cfg.Backend.InMemory.SnapshotPath,
+		sweeperInterval: cfg.Backend.InMemory.SweeperInterval,
 	}
+	be.epoch.Store(1)
+	be.table.Store(newShardTable[V](shardCount, cfg.Backend.InMemory.Capacity, cfg.Backend.InMemory.DefaultTTL, cfg.Clock))
+
+	if be.snapshotPath != "" {
+		if err := be.restoreFromFile(be.snapshotPath); err != nil {
+			log.Warn("failed to restore cache snapshot", "path", be.snapshotPath, "error", err)
+		}
+	}
+
+	be.startSweepers(be.table.Load())
 
-	for i := range shards {
-		go be.runSweeper(&shards[i], cfg.Backend.InMemory.SweeperInterval)
+	if pressureCfg := cfg.Backend.InMemory.MemoryPressure; pressureCfg != nil {
+		if limit := mempressure.Limit(pressureCfg.SoftLimit); limit > 0 {
+			log.Info("starting memory pressure watcher", "limit", limit, "threshold", pressureCfg.Threshold)
+			be.pressure = newPressureWatcher(pressureCfg, limit)
+			be.wg.Add(1)
+			go be.runPressureWatcher(be.clock.NewTicker(pressureCfg.Interval))
+		} else {
+			log.Warn("memory pressure watcher disabled: no soft limit or GOMEMLIMIT configured")
+		}
 	}
 
 	if cfg.Invalidation != nil {
 		log.Info("initializing invalidation", "type", cfg.Invalidation.Type)
-		invalidatorConfig := getInvalidatorConfig(cfg.Invalidation)
-		if inv, err := invalidation.NewInvalidator(cfg.Invalidation.Type, invalidatorConfig); err == nil {
-			be.invalidator = inv
+		inv := cfg.Invalidation.PubSub
+		if inv == nil {
+			invalidatorConfig := getInvalidatorConfig(cfg.Invalidation)
+			inv, err = invalidation.NewInvalidatorWithLogger(cfg.Invalidation.Type, invalidatorConfig, log.With("driver", cfg.Invalidation.Type))
+		}
+		if err == nil {
+			onGap := func() {
+				if err := be.Clear(option.WithClearDelivery(option.DeliveryLocal)); err != nil {
+					log.Warn("failed to clear cache after invalidation gap", "error", err)
+				}
+			}
+			opts := append(cfg.Invalidation.CoordinatorOptions(onGap),
+				invalidation.WithDeleteIfOlder(be.deleteIfOlder),
+				invalidation.WithClearHandler(be.handleClearMessage))
+			be.invalidator = invalidation.NewCoordinator(ctx, inv, log, opts...)
+			be.delivery = cfg.Invalidation.DeliveryDefaults()
 			log.Info("invalidation initialized successfully", "type", cfg.Invalidation.Type)
+			be.wg.Add(2)
 			go func() {
-				log.Debug("starting invalidation subscription")
-				err = inv.Subscribe(ctx, func(key string) error {
-					log.Debug("received invalidation", "key", key)
-					return be.handleInvalidationMessage(key)
-				})
-				if err != nil {
-					log.Info("invalidation subscription ended", "error", err)
-				}
+				defer be.wg.Done()
+				be.invalidator.Run(be.handleInvalidationMessage)
+			}()
+			go func() {
+				defer be.wg.Done()
+				be.invalidator.RunPublisher()
 			}()
 		} else {
 			log.Error("failed to create invalidator", "type", cfg.Invalidation.Type, "error", err)
+			cancel()
+			be.wg.Wait()
 			return nil, fmt.Errorf("failed to create invalidator: %w", err)
 		}
 	}
@@ -1,15 +1,23 @@
 package inmemory
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
 	"github.com/halilbulentorhon/invacache-go/backend/option"
 	"github.com/halilbulentorhon/invacache-go/config"
 	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock/clocktest"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
 )
 
 func TestNewInMemoryBackend(t *testing.T) {
@@ -56,7 +64,7 @@ func TestGetNonExistentKey(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for non-existent key")
 	}
-	if err.Error() != constant.ErrKeyNotFound+": nonexistent" {
+	if err != constant.ErrNotFound {
 		t.Errorf("expected 'key not found' error, got: %v", err)
 	}
 }
@@ -80,7 +88,7 @@ func TestSetAndGet(t *testing.T) {
 }
 
 func TestSetWithTTL(t *testing.T) {
-	cache := createTestCache[string](t)
+	cache, clk := createFakeClockCache[string](t)
 	defer cache.Close()
 
 	err := cache.Set("key1", "value1", option.WithTTL(100*time.Millisecond))
@@ -96,7 +104,7 @@ func TestSetWithTTL(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	_, err = cache.Get("key1")
 	if err == nil {
@@ -105,7 +113,7 @@ func TestSetWithTTL(t *testing.T) {
 }
 
 func TestSetWithNoExpiration(t *testing.T) {
-	cache := createTestCache[string](t)
+	cache, clk := createFakeClockCache[string](t)
 	defer cache.Close()
 
 	err := cache.Set("key1", "value1", option.WithNoExpiration())
@@ -113,7 +121,7 @@ func TestSetWithNoExpiration(t *testing.T) {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := cache.Get("key1")
 	if err != nil {
@@ -690,6 +698,7 @@ func TestHandleInvalidationMessageDelete(t *testing.T) {
 }
 
 func TestSetWithDefaultTTL(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -699,6 +708,7 @@ func TestSetWithDefaultTTL(t *testing.T) {
 				Ttl:             "100ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -720,7 +730,7 @@ func TestSetWithDefaultTTL(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	_, err = cache.Get("key1")
 	if err == nil {
@@ -729,6 +739,7 @@ func TestSetWithDefaultTTL(t *testing.T) {
 }
 
 func TestSetWithDefaultTTLOverriddenByOption(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -738,6 +749,7 @@ func TestSetWithDefaultTTLOverriddenByOption(t *testing.T) {
 				Ttl:             "50ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -751,7 +763,7 @@ func TestSetWithDefaultTTLOverriddenByOption(t *testing.T) {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	value, err := cache.Get("key1")
 	if err != nil {
@@ -763,6 +775,7 @@ func TestSetWithDefaultTTLOverriddenByOption(t *testing.T) {
 }
 
 func TestSetWithDefaultTTLAndNoExpiration(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -772,6 +785,7 @@ func TestSetWithDefaultTTLAndNoExpiration(t *testing.T) {
 				Ttl:             "50ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -785,7 +799,7 @@ func TestSetWithDefaultTTLAndNoExpiration(t *testing.T) {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	value, err := cache.Get("key1")
 	if err != nil {
@@ -797,6 +811,7 @@ func TestSetWithDefaultTTLAndNoExpiration(t *testing.T) {
 }
 
 func TestNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -806,6 +821,7 @@ func TestNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 				Ttl:             "100ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -824,7 +840,7 @@ func TestNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 		t.Fatalf("unexpected error setting key2: %v", err)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	value1, err := cache.Get("key1")
 	if err != nil {
@@ -841,6 +857,7 @@ func TestNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 }
 
 func TestUpdateWithNoExpiration(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -850,6 +867,7 @@ func TestUpdateWithNoExpiration(t *testing.T) {
 				Ttl:             "50ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -863,14 +881,14 @@ func TestUpdateWithNoExpiration(t *testing.T) {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(30 * time.Millisecond)
+	clk.Advance(30 * time.Millisecond)
 
 	err = cache.Set("key1", "value2", option.WithNoExpiration())
 	if err != nil {
 		t.Fatalf("unexpected error updating key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := cache.Get("key1")
 	if err != nil {
@@ -882,6 +900,7 @@ func TestUpdateWithNoExpiration(t *testing.T) {
 }
 
 func TestDefaultTTLAppliedWhenNoOption(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -891,6 +910,7 @@ func TestDefaultTTLAppliedWhenNoOption(t *testing.T) {
 				Ttl:             "100ms",
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[string](cfg)
@@ -904,7 +924,7 @@ func TestDefaultTTLAppliedWhenNoOption(t *testing.T) {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := cache.Get("key1")
 	if err != nil {
@@ -914,7 +934,7 @@ func TestDefaultTTLAppliedWhenNoOption(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(70 * time.Millisecond)
 
 	_, err = cache.Get("key1")
 	if err == nil {
@@ -922,7 +942,31 @@ func TestDefaultTTLAppliedWhenNoOption(t *testing.T) {
 	}
 }
 
-func createTestCache[V any](t *testing.T) backend.Cache[V] {
+func createTestCache[V any](t testing.TB) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        100,
+				SweeperInterval: 1 * time.Minute,
+			},
+		},
+	}
+
+	cache, err := NewInMemoryBackend[V](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+
+	return cache
+}
+
+func createFakeClockCache[V any](t testing.TB) (backend.Cache[V], *clocktest.Fake) {
+	clk := clocktest.NewFake(time.Unix(0, 0))
+	return createTestCacheWithClock[V](t, clk), clk
+}
+
+func createTestCacheWithClock[V any](t testing.TB, clk clock.Clock) backend.Cache[V] {
 	cfg := config.InvaCacheConfig{
 		Backend: &config.BackendConfig{
 			InMemory: &config.InMemoryConfig{
@@ -931,6 +975,7 @@ func createTestCache[V any](t *testing.T) backend.Cache[V] {
 				SweeperInterval: 1 * time.Minute,
 			},
 		},
+		Clock: clk,
 	}
 
 	cache, err := NewInMemoryBackend[V](cfg)
@@ -940,3 +985,177 @@ func createTestCache[V any](t *testing.T) backend.Cache[V] {
 
 	return cache
 }
+
+func TestLenAndKeys(t *testing.T) {
+	cache, clk := createFakeClockCache[string](t)
+	defer cache.Close()
+
+	if cache.Len() != 0 {
+		t.Errorf("expected empty cache, got %d", cache.Len())
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := cache.Set(fmt.Sprintf("key%d", i), "value"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_ = cache.Set("expired", "value", option.WithTTL(10*time.Millisecond))
+	clk.Advance(20 * time.Millisecond)
+
+	if cache.Len() != 10 {
+		t.Errorf("expected 10 entries, got %d", cache.Len())
+	}
+
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if len(keys) != 10 {
+		t.Fatalf("expected 10 keys, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if key == "expired" {
+			t.Error("expired key should not be listed")
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	cache := createTestCache[int](t)
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	sum := 0
+	cache.Range(func(key string, value int) bool {
+		sum += value
+		return true
+	})
+	if sum != 190 {
+		t.Errorf("expected sum 190, got %d", sum)
+	}
+
+	visited := 0
+	cache.Range(func(key string, value int) bool {
+		visited++
+		return visited < 5
+	})
+	if visited != 5 {
+		t.Errorf("expected range to stop after 5 entries, got %d", visited)
+	}
+}
+
+func TestRangeAllowsMutation(t *testing.T) {
+	cache := createTestCache[int](t)
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	cache.Range(func(key string, value int) bool {
+		if value%2 == 0 {
+			_ = cache.Delete(key)
+		}
+		return true
+	})
+
+	if cache.Len() != 10 {
+		t.Errorf("expected 10 entries after selective purge, got %d", cache.Len())
+	}
+}
+
+func TestNewInMemoryBackendWithCodec(t *testing.T) {
+	cfg := config.InvaCacheConfig{Codec: constant.JSONCodec}
+
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	cfg.Codec = "unknown"
+	if _, err = NewInMemoryBackend[string](cfg); err == nil {
+		t.Error("expected error for unknown codec")
+	}
+}
+
+func TestSweeperRunsOnClockTicks(t *testing.T) {
+	cache, clk := createFakeClockCache[string](t)
+	defer cache.Close()
+	be := cache.(*inMemoryBackend[string])
+
+	_ = cache.Set("key1", "value1", option.WithTTL(time.Second))
+	clk.Advance(2 * time.Second)
+
+	shardCount := func() int {
+		shard := be.lockShard("key1")
+		defer shard.mu.Unlock()
+		return shard.count
+	}
+	if shardCount() != 1 {
+		t.Fatal("expected expired entry to stay until the sweeper runs")
+	}
+
+	clk.Advance(time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for shardCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected sweeper to remove the expired entry after a tick")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type droppingPubSub struct {
+	drop         chan struct{}
+	resubscribed chan struct{}
+	calls        atomic.Int32
+}
+
+func (p *droppingPubSub) Publish(context.Context, string) error { return nil }
+
+func (p *droppingPubSub) Subscribe(ctx context.Context, _ invalidation.InvalidationHandler) error {
+	if p.calls.Add(1) == 1 {
+		<-p.drop
+		return errors.New("connection reset")
+	}
+	close(p.resubscribed)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *droppingPubSub) Close() error { return nil }
+
+func TestClearOnGapAfterResubscribe(t *testing.T) {
+	pubsub := &droppingPubSub{drop: make(chan struct{}), resubscribed: make(chan struct{})}
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{InMemory: &config.InMemoryConfig{Capacity: 100, ShardCount: 4}},
+		Invalidation: &config.InvalidationConfig{
+			PubSub:      pubsub,
+			Resubscribe: &config.ResubscribeConfig{InitialBackoff: time.Millisecond, ClearOnGap: true},
+		},
+		Logger: logger.Discard(),
+	}
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+	reporter := cache.(backend.InvalidationReporter)
+
+	_ = cache.Set("stale", "value")
+	close(pubsub.drop)
+
+	select {
+	case <-pubsub.resubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the subscription to be restarted")
+	}
+	if _, err := cache.Get("stale"); !errors.Is(err, constant.ErrNotFound) {
+		t.Errorf("expected cache to be cleared after the gap, got %v", err)
+	}
+	if state := reporter.InvalidationState(); state != invalidation.StateUnconfirmed {
+		t.Errorf("expected unconfirmed state for a driver without SubscribeReady, got %v", state)
+	}
+}
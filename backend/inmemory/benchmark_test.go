@@ -0,0 +1,187 @@
+package inmemory
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/config"
+)
+
+func TestHotPathAllocations(t *testing.T) {
+	cache := createTestCache[string](t)
+	defer cache.Close()
+
+	_ = cache.Set("key1", "value1")
+	_ = cache.Set("recycled", "value")
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{name: "get hit", fn: func() { _, _ = cache.Get("key1") }},
+		{name: "get miss", fn: func() { _, _ = cache.Get("missing") }},
+		{name: "set existing", fn: func() { _ = cache.Set("key1", "value1") }},
+		{name: "delete missing", fn: func() { _ = cache.Delete("missing") }},
+		{name: "delete and set", fn: func() {
+			_ = cache.Delete("recycled")
+			_ = cache.Set("recycled", "value")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tt.fn); allocs != 0 {
+				t.Errorf("expected 0 allocs/op, got %v", allocs)
+			}
+		})
+	}
+}
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := map[int]int{1: 1, 2: 2, 3: 4, 4: 4, 5: 8, 16: 16, 17: 32}
+	for in, want := range tests {
+		if got := nextPowerOfTwo(in); got != want {
+			t.Errorf("nextPowerOfTwo(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestShardCountFor(t *testing.T) {
+	tests := []struct{ requested, capacity, want int }{
+		{requested: 3, capacity: 10, want: 4},
+		{requested: 8, capacity: 9, want: 8},
+		{requested: 5, capacity: 6, want: 4},
+		{requested: 5, capacity: 8, want: 4},
+	}
+	for _, tt := range tests {
+		got := shardCountFor(tt.requested, tt.capacity)
+		if got != tt.want {
+			t.Errorf("shardCountFor(%d, %d) = %d, want %d", tt.requested, tt.capacity, got, tt.want)
+		}
+		total := 0
+		for idx := 0; idx < got; idx++ {
+			total += shardCapacity(tt.capacity, got, idx)
+		}
+		if total != tt.capacity {
+			t.Errorf("expected %d shards to hold exactly %d entries, got %d", got, tt.capacity, total)
+		}
+	}
+}
+
+func TestShardCountRoundedToPowerOfTwo(t *testing.T) {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      3,
+				Capacity:        10,
+				SweeperInterval: time.Minute,
+			},
+		},
+	}
+
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	defer cache.Close()
+
+	table := cache.(*inMemoryBackend[string]).table.Load()
+	if len(table.shards) != 4 || table.mask != 3 {
+		t.Errorf("expected 4 shards with mask 3, got %d shards with mask %d", len(table.shards), table.mask)
+	}
+
+	total := 0
+	for i := range table.shards {
+		total += table.shards[i].capacity
+	}
+	if total != 10 {
+		t.Errorf("expected total capacity 10, got %d", total)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkGetHit(b *testing.B) {
+	cache := createTestCache[string](b)
+	defer cache.Close()
+
+	keys := benchmarkKeys(64)
+	for _, key := range keys {
+		_ = cache.Set(key, "value")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cache.Get(keys[i&63])
+	}
+}
+
+func BenchmarkGetMiss(b *testing.B) {
+	cache := createTestCache[string](b)
+	defer cache.Close()
+
+	keys := benchmarkKeys(64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cache.Get(keys[i&63])
+	}
+}
+
+func BenchmarkSetExisting(b *testing.B) {
+	cache := createTestCache[string](b)
+	defer cache.Close()
+
+	keys := benchmarkKeys(64)
+	for _, key := range keys {
+		_ = cache.Set(key, "value")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cache.Set(keys[i&63], "value")
+	}
+}
+
+func BenchmarkDelete(b *testing.B) {
+	cache := createTestCache[string](b)
+	defer cache.Close()
+
+	keys := benchmarkKeys(64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = cache.Delete(keys[i&63])
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	cache := createTestCache[string](b)
+	defer cache.Close()
+
+	keys := benchmarkKeys(64)
+	for _, key := range keys {
+		_ = cache.Set(key, "value")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = cache.Get(keys[i&63])
+			i++
+		}
+	})
+}
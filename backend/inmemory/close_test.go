@@ -0,0 +1,220 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type closeTestPubSub struct {
+	closed     chan struct{}
+	closeOnce  sync.Once
+	closeCalls atomic.Int32
+	exited     atomic.Bool
+	// ignoreCtx makes Subscribe block until Close instead of ctx.
+	ignoreCtx bool
+}
+
+func newCloseTestPubSub(ignoreCtx bool) *closeTestPubSub {
+	return &closeTestPubSub{closed: make(chan struct{}), ignoreCtx: ignoreCtx}
+}
+
+func (p *closeTestPubSub) Publish(context.Context, string) error { return nil }
+
+func (p *closeTestPubSub) Subscribe(ctx context.Context, _ invalidation.InvalidationHandler) error {
+	defer p.exited.Store(true)
+	if p.ignoreCtx {
+		<-p.closed
+		return nil
+	}
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	return ctx.Err()
+}
+
+func (p *closeTestPubSub) Close() error {
+	p.closeCalls.Add(1)
+	p.closeOnce.Do(func() { close(p.closed) })
+	return nil
+}
+
+func createClosableCache(t *testing.T, pubsub invalidation.PubSub) *inMemoryBackend[string] {
+	t.Helper()
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        100,
+				SweeperInterval: time.Minute,
+				MemoryPressure:  &config.MemoryPressureConfig{SoftLimit: 1 << 40, Interval: time.Minute},
+			},
+		},
+		Invalidation: &config.InvalidationConfig{PubSub: pubsub},
+		Logger:       logger.Discard(),
+	}
+
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	return cache.(*inMemoryBackend[string])
+}
+
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("expected at most %d goroutines, got %d:\n%s", baseline, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	pubsub := newCloseTestPubSub(false)
+	cache := createClosableCache(t, pubsub)
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error on second close: %v", err)
+	}
+	if calls := pubsub.closeCalls.Load(); calls != 1 {
+		t.Errorf("expected driver to be closed once, got %d", calls)
+	}
+}
+
+func TestOperationsAfterCloseReturnErrClosed(t *testing.T) {
+	cache := createClosableCache(t, newCloseTestPubSub(false))
+	_ = cache.Set("kept", "value")
+	_ = cache.Close()
+
+	loader := func(string) (string, time.Duration, error) {
+		t.Error("loader should not run after close")
+		return "", 0, nil
+	}
+	checks := map[string]error{
+		"set":       cache.Set("key", "value"),
+		"delete":    cache.Delete("kept"),
+		"clear":     cache.Clear(),
+		"resize":    cache.Resize(200),
+		"reshard":   cache.Reshard(16),
+		"getOrLoad": func() error { _, err := cache.GetOrLoad("missing", loader); return err }(),
+	}
+	for name, err := range checks {
+		if !errors.Is(err, constant.ErrClosed) {
+			t.Errorf("%s: expected ErrClosed, got %v", name, err)
+		}
+	}
+
+	if value, err := cache.Get("kept"); err != nil || value != "value" {
+		t.Errorf("expected reads to keep working after close, got %q (%v)", value, err)
+	}
+}
+
+func TestCloseWaitsForBackgroundGoroutines(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	pubsub := newCloseTestPubSub(false)
+	cache := createClosableCache(t, pubsub)
+
+	if err := cache.Reshard(8); err != nil {
+		t.Fatalf("unexpected error resharding: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !pubsub.exited.Load() {
+		t.Error("expected Close to wait for the subscription to exit")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestFailedConstructorStopsBackgroundGoroutines(t *testing.T) {
+	invalidation.RegisterInvalidator("close-test-failing", func(interface{}) (invalidation.PubSub, error) {
+		return nil, errors.New("bus unavailable")
+	})
+	baseline := runtime.NumGoroutine()
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        100,
+				SweeperInterval: time.Minute,
+				MemoryPressure:  &config.MemoryPressureConfig{SoftLimit: 1 << 40, Interval: time.Minute},
+			},
+		},
+		Invalidation: &config.InvalidationConfig{Type: "close-test-failing"},
+		Logger:       logger.Discard(),
+	}
+
+	if _, err := NewInMemoryBackend[string](cfg); err == nil {
+		t.Fatal("expected the failing invalidator to fail the constructor")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestCloseContextDeadline(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	pubsub := newCloseTestPubSub(true)
+	cache := createClosableCache(t, pubsub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var closer backend.GracefulCloser = cache
+	if err := closer.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if pubsub.closeCalls.Load() != 1 {
+		t.Error("expected driver to be closed after the deadline")
+	}
+	if err := cache.Close(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected later Close to return the first result, got %v", err)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestHealthAndWaitReady(t *testing.T) {
+	cache := createClosableCache(t, newCloseTestPubSub(false))
+	var reporter backend.HealthReporter = cache
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := reporter.WaitReady(ctx); err != nil {
+		t.Fatalf("unexpected error waiting for readiness: %v", err)
+	}
+
+	health := reporter.Health()
+	if health.Backend != constant.InMemoryBackend || health.Closed || health.Invalidation.State != invalidation.StateUnconfirmed {
+		t.Errorf("unexpected health before close: %+v", health)
+	}
+
+	_ = cache.Close()
+	health = reporter.Health()
+	if !health.Closed || health.Invalidation.State != invalidation.StateClosed {
+		t.Errorf("unexpected health after close: %+v", health)
+	}
+
+	plain := createTestCache[string](t)
+	defer plain.Close()
+	if err := plain.(backend.HealthReporter).WaitReady(ctx); err != nil {
+		t.Errorf("expected a cache without invalidation to be ready, got %v", err)
+	}
+	if state := plain.(backend.HealthReporter).Health().Invalidation.State; state != invalidation.StateDisabled {
+		t.Errorf("expected disabled invalidation, got %v", state)
+	}
+}
@@ -0,0 +1,187 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+)
+
+// recordingPubSub records published keys and fails every publish while fail
+// is set. The handler is captured so tests can deliver invalidations.
+type recordingPubSub struct {
+	handler   invalidation.InvalidationHandler
+	ready     chan struct{}
+	published []string
+	mu        sync.Mutex
+	fail      bool
+	once      sync.Once
+}
+
+func newRecordingPubSub() *recordingPubSub {
+	return &recordingPubSub{ready: make(chan struct{})}
+}
+
+func (p *recordingPubSub) Publish(_ context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return errors.New("bus unavailable")
+	}
+	p.published = append(p.published, key)
+	return nil
+}
+
+func (p *recordingPubSub) Subscribe(ctx context.Context, handler invalidation.InvalidationHandler) error {
+	p.mu.Lock()
+	p.handler = handler
+	p.mu.Unlock()
+	p.once.Do(func() { close(p.ready) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *recordingPubSub) Close() error { return nil }
+
+func (p *recordingPubSub) keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func createDeliveryCache(t *testing.T, pubsub *recordingPubSub, delivery *config.DeliveryConfig) *inMemoryBackend[string] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{InMemory: &config.InMemoryConfig{ShardCount: 2, Capacity: 100, SweeperInterval: time.Minute}},
+		Invalidation: &config.InvalidationConfig{
+			PubSub:   pubsub,
+			Publish:  &config.PublishConfig{MaxRetries: -1},
+			Delivery: delivery,
+		},
+	}
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	<-pubsub.ready
+	return cache.(*inMemoryBackend[string])
+}
+
+func TestPerCallDelivery(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cache := createDeliveryCache(t, pubsub, nil)
+
+	_ = cache.Set("local", "v")
+	_ = cache.Set("explicit-local", "v", option.WithDelivery(option.DeliveryLocal))
+	_ = cache.Set("async", "v", option.WithDelivery(option.DeliveryAsync))
+	if err := cache.Set("sync", "v", option.WithDelivery(option.DeliverySync)); err != nil {
+		t.Fatalf("unexpected sync error: %v", err)
+	}
+	_ = cache.Flush(context.Background())
+
+	keys := pubsub.keys()
+	if len(keys) != 2 {
+		t.Fatalf("expected only async and sync writes to publish, got %v", keys)
+	}
+	for _, key := range keys {
+		if key != "async" && key != "sync" {
+			t.Errorf("unexpected published key %q", key)
+		}
+	}
+}
+
+func TestSyncDeliveryReturnsPublishError(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cache := createDeliveryCache(t, pubsub, nil)
+	pubsub.mu.Lock()
+	pubsub.fail = true
+	pubsub.mu.Unlock()
+
+	_ = cache.Set("perm:1", "admin")
+	if err := cache.Set("perm:1", "viewer", option.WithDelivery(option.DeliverySync)); err == nil {
+		t.Error("expected sync delivery to return the publish error")
+	}
+	if err := cache.Set("perm:2", "viewer", option.WithDelivery(option.DeliveryAsync)); err != nil {
+		t.Errorf("expected async delivery to hide the publish error, got %v", err)
+	}
+	if err := cache.Clear(option.WithClearDelivery(option.DeliverySync)); err == nil {
+		t.Error("expected sync clear to return the publish error")
+	}
+}
+
+func TestCacheLevelDeliveryDefault(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cache := createDeliveryCache(t, pubsub, &config.DeliveryConfig{Delete: "async"})
+
+	_ = cache.Set("user:1", "v")
+	_ = cache.Set("user:2", "v")
+	_ = cache.Delete("user:1")
+	_ = cache.Delete("user:2", option.WithDeleteDelivery(option.DeliveryLocal))
+
+	pubsub.mu.Lock()
+	handler := pubsub.handler
+	pubsub.mu.Unlock()
+	_ = cache.Set("user:3", "v")
+	if err := handler("user:3"); err != nil {
+		t.Fatalf("unexpected error handling invalidation: %v", err)
+	}
+	_ = cache.Flush(context.Background())
+
+	if keys := pubsub.keys(); len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("expected only the default delete to broadcast, got %v", keys)
+	}
+	if _, err := cache.Get("user:3"); err == nil {
+		t.Error("expected the received invalidation to delete user:3 locally")
+	}
+}
+
+func TestStaleInvalidationKeepsNewerWrite(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{InMemory: &config.InMemoryConfig{ShardCount: 2, Capacity: 100, SweeperInterval: time.Minute}},
+		Invalidation: &config.InvalidationConfig{
+			PubSub:   pubsub,
+			Envelope: &config.EnvelopeConfig{DedupeWindow: time.Minute},
+		},
+	}
+	created, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	t.Cleanup(func() { _ = created.Close() })
+	<-pubsub.ready
+	cache := created.(*inMemoryBackend[string])
+
+	pubsub.mu.Lock()
+	handler := pubsub.handler
+	pubsub.mu.Unlock()
+
+	_ = cache.Set("user:1", "new")
+	stale := invalidation.Message{ID: "peer-1", Origin: "peer", Key: "user:1", Stamp: 1}
+	if err := handler(invalidation.EncodeMessage(stale)); err != nil {
+		t.Fatalf("unexpected error handling invalidation: %v", err)
+	}
+	if value, err := cache.Get("user:1"); err != nil || value != "new" {
+		t.Errorf("expected the newer write to survive a stale invalidation, got %q, %v", value, err)
+	}
+
+	newer := invalidation.Message{ID: "peer-2", Origin: "peer", Key: "user:1", Stamp: cache.invalidator.Stamp() + 1}
+	_ = handler(invalidation.EncodeMessage(newer))
+	if _, err := cache.Get("user:1"); err == nil {
+		t.Error("expected a newer invalidation to delete user:1")
+	}
+
+	readAt := cache.Stamp()
+	peerWrite := cache.Stamp()
+	_ = cache.Set("user:2", "copy", option.WithStamp(readAt))
+	older := invalidation.Message{ID: "peer-3", Origin: "peer", Key: "user:2", Stamp: peerWrite}
+	_ = handler(invalidation.EncodeMessage(older))
+	if _, err := cache.Get("user:2"); err == nil {
+		t.Error("expected an invalidation newer than the copy's stamp to delete it")
+	}
+}
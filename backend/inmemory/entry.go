@@ -1,6 +1,10 @@
 package inmemory
 
-import "time"
+import (
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+)
 
 type Entry[V any] struct {
 	ExpiresAt time.Time
@@ -8,8 +12,15 @@ type Entry[V any] struct {
 	prev      *Entry[V]
 	next      *Entry[V]
 	Key       string
+	// writeStamp is the invalidation stamp of the write that stored Value,
+	// zero when unknown.
+	writeStamp int64
 }
 
 func (e *Entry[V]) IsExpired() bool {
 	return !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt)
 }
+
+func (e *Entry[V]) expired(clk clock.Clock) bool {
+	return !e.ExpiresAt.IsZero() && clk.Now().After(e.ExpiresAt)
+}
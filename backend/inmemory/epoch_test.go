@@ -0,0 +1,115 @@
+package inmemory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+func TestClearRefusesLoadStartedBefore(t *testing.T) {
+	cache := createTestCache[string](t)
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		value, _ := cache.GetOrLoad("user:1", func(string) (string, time.Duration, error) {
+			close(loading)
+			<-release
+			return "before-clear", 0, nil
+		})
+		done <- value
+	}()
+
+	<-loading
+	_ = cache.Clear()
+	close(release)
+	if value := <-done; value != "before-clear" {
+		t.Errorf("expected the loader's value to be returned, got %q", value)
+	}
+	if _, err := cache.Get("user:1"); err == nil {
+		t.Error("expected a load that started before Clear not to be stored")
+	}
+}
+
+func TestSetWithStaleEpoch(t *testing.T) {
+	cache := createTestCache[string](t)
+	reporter := cache.(backend.EpochReporter)
+
+	epoch := reporter.Epoch()
+	if err := cache.Set("user:1", "v1", option.WithEpoch(epoch)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = cache.Clear()
+	if reporter.Epoch() != epoch+1 {
+		t.Errorf("expected Clear to advance the epoch from %d, got %d", epoch, reporter.Epoch())
+	}
+	if err := cache.Set("user:1", "v2", option.WithEpoch(epoch)); !errors.Is(err, constant.ErrStaleEpoch) {
+		t.Errorf("expected ErrStaleEpoch, got %v", err)
+	}
+	if err := cache.Set("user:1", "v3"); err != nil {
+		t.Errorf("expected an untagged write to succeed, got %v", err)
+	}
+}
+
+func TestClearNamespace(t *testing.T) {
+	cache := createTestCache[string](t)
+	for _, key := range []string{"user:1", "user:2", "users:1", "order:1"} {
+		_ = cache.Set(key, "v")
+	}
+
+	_ = cache.Clear(option.WithNamespace("user"))
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Errorf("expected users:1 and order:1 to remain, got %v", keys)
+	}
+}
+
+func TestReceivedClearAdvancesEpoch(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cache := createDeliveryCache(t, pubsub, nil)
+	pubsub.mu.Lock()
+	handler := pubsub.handler
+	pubsub.mu.Unlock()
+
+	_ = cache.Set("user:1", "v")
+	_ = cache.Set("order:1", "v")
+	clear := invalidation.Message{Namespace: "user", Epoch: 10}
+	if err := handler(invalidation.EncodeMessage(clear)); err != nil {
+		t.Fatalf("unexpected error handling clear: %v", err)
+	}
+
+	if _, err := cache.Get("user:1"); err == nil {
+		t.Error("expected the namespace clear to remove user:1")
+	}
+	if _, err := cache.Get("order:1"); err != nil {
+		t.Error("expected order:1 to survive a clear of namespace user")
+	}
+	if epoch := cache.Epoch(); epoch != 10 {
+		t.Errorf("expected the epoch to move to the sender's, got %d", epoch)
+	}
+	if keys := pubsub.keys(); len(keys) != 0 {
+		t.Errorf("expected a received clear not to be rebroadcast, got %v", keys)
+	}
+}
+
+func TestClearPublishesNamespace(t *testing.T) {
+	pubsub := newRecordingPubSub()
+	cache := createDeliveryCache(t, pubsub, nil)
+
+	if err := cache.Clear(option.WithNamespace("user"), option.WithClearDelivery(option.DeliverySync)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys := pubsub.keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected one clear message, got %v", keys)
+	}
+	messages := invalidation.DecodeMessages(keys[0])
+	if len(messages) != 1 || !invalidation.IsClearEvent(messages[0].Key) || messages[0].Namespace != "user" {
+		t.Errorf("unexpected clear message %+v", messages)
+	}
+}
@@ -0,0 +1,158 @@
+package inmemory
+
+import (
+	"sync"
+
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+	"github.com/halilbulentorhon/invacache-go/pkg/mempressure"
+)
+
+type pressureWatcher struct {
+	cfg    *config.MemoryPressureConfig
+	sample func() mempressure.Sample
+	stats  mempressure.Stats
+	// shedCycle is the GC cycle count at the last shed. The live heap it
+	// measured does not drop until a later cycle completes.
+	shedCycle uint64
+	shedding  bool
+	mu        sync.Mutex
+}
+
+func newPressureWatcher(cfg *config.MemoryPressureConfig, limit uint64) *pressureWatcher {
+	return &pressureWatcher{
+		cfg: cfg,
+		sample: func() mempressure.Sample {
+			return mempressure.Read(limit)
+		},
+	}
+}
+
+func (i *inMemoryBackend[V]) runPressureWatcher(ticker clock.Ticker) {
+	defer i.wg.Done()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.ctx.Done():
+			return
+		case <-ticker.C():
+			i.checkPressure()
+		}
+	}
+}
+
+func (i *inMemoryBackend[V]) checkPressure() {
+	cfg := i.pressure.cfg
+	sample := i.pressure.sample()
+	ratio := sample.Ratio()
+
+	switch {
+	case ratio >= cfg.Threshold && !i.pressure.awaitingGC(sample):
+		i.shed(sample)
+	case ratio < cfg.RecoverThreshold:
+		i.growCapacity(sample)
+	default:
+		i.pressure.mu.Lock()
+		i.pressure.stats.HeapBytes = sample.HeapBytes
+		i.pressure.stats.Limit = sample.Limit
+		i.pressure.mu.Unlock()
+	}
+}
+
+// awaitingGC reports whether no GC cycle has completed since the last shed,
+// so sample cannot yet show what the shed freed.
+func (p *pressureWatcher) awaitingGC(sample mempressure.Sample) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.shedding && sample.GCCycles == p.shedCycle
+}
+
+func (i *inMemoryBackend[V]) shed(sample mempressure.Sample) {
+	cfg := i.pressure.cfg
+	evicted, capacity := 0, 0
+	i.resizeMu.RLock()
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.Lock()
+		evicted += shards[idx].shed(cfg.ShedFraction, cfg.MinCapacityFraction)
+		capacity += shards[idx].capacity
+		shards[idx].mu.Unlock()
+	}
+	i.resizeMu.RUnlock()
+
+	event := mempressure.Event{
+		At:        i.clock.Now(),
+		HeapBytes: sample.HeapBytes,
+		Limit:     sample.Limit,
+		Evicted:   evicted,
+		Capacity:  capacity,
+	}
+
+	i.pressure.mu.Lock()
+	i.pressure.stats.HeapBytes = sample.HeapBytes
+	i.pressure.stats.Limit = sample.Limit
+	i.pressure.stats.UnderPressure = true
+	i.pressure.stats.LastShedAt = event.At
+	i.pressure.stats.ShedEvents++
+	i.pressure.stats.EvictedEntries += uint64(evicted)
+	i.pressure.shedCycle = sample.GCCycles
+	i.pressure.shedding = true
+	i.pressure.mu.Unlock()
+
+	i.logger.Warn("shedding cache entries under memory pressure",
+		"heap_bytes", sample.HeapBytes,
+		"limit", sample.Limit,
+		"evicted", evicted,
+		"capacity", capacity)
+
+	if cfg.OnShed != nil {
+		cfg.OnShed(event)
+	}
+}
+
+func (i *inMemoryBackend[V]) growCapacity(sample mempressure.Sample) {
+	cfg := i.pressure.cfg
+	capacity, configured := 0, 0
+	i.resizeMu.RLock()
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.Lock()
+		shards[idx].grow(cfg.ShedFraction)
+		capacity += shards[idx].capacity
+		configured += shards[idx].maxCapacity
+		shards[idx].mu.Unlock()
+	}
+	i.resizeMu.RUnlock()
+
+	i.pressure.mu.Lock()
+	recovered := i.pressure.stats.UnderPressure && capacity == configured
+	i.pressure.stats.HeapBytes = sample.HeapBytes
+	i.pressure.stats.Limit = sample.Limit
+	i.pressure.stats.UnderPressure = capacity < configured
+	i.pressure.mu.Unlock()
+
+	if recovered {
+		i.logger.Info("memory pressure relieved, cache capacity restored", "capacity", capacity)
+	}
+}
+
+func (i *inMemoryBackend[V]) MemoryPressureStats() mempressure.Stats {
+	var stats mempressure.Stats
+	if i.pressure != nil {
+		i.pressure.mu.Lock()
+		stats = i.pressure.stats
+		i.pressure.mu.Unlock()
+	}
+
+	i.resizeMu.RLock()
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.RLock()
+		stats.Capacity += shards[idx].capacity
+		stats.ConfiguredCapacity += shards[idx].maxCapacity
+		shards[idx].mu.RUnlock()
+	}
+	i.resizeMu.RUnlock()
+	return stats
+}
@@ -0,0 +1,219 @@
+package inmemory
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/pkg/mempressure"
+)
+
+func createPressureCache(t *testing.T, onShed func(mempressure.Event)) *inMemoryBackend[string] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        100,
+				SweeperInterval: time.Minute,
+				MemoryPressure: &config.MemoryPressureConfig{
+					SoftLimit: 1 << 40,
+					Interval:  time.Hour,
+					OnShed:    onShed,
+				},
+			},
+		},
+	}
+
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	be := cache.(*inMemoryBackend[string])
+	if be.pressure == nil {
+		t.Fatal("expected memory pressure watcher to be started")
+	}
+	return be
+}
+
+var gcCycles atomic.Uint64
+
+// setHeapRatio makes every later sample report ratio after a new GC cycle.
+func setHeapRatio(be *inMemoryBackend[string], ratio float64) {
+	be.pressure.sample = func() mempressure.Sample {
+		return mempressure.Sample{HeapBytes: uint64(ratio * 1000), Limit: 1000, GCCycles: gcCycles.Add(1)}
+	}
+}
+
+func TestPressureShedsLRUTail(t *testing.T) {
+	var events []mempressure.Event
+	be := createPressureCache(t, func(event mempressure.Event) {
+		events = append(events, event)
+	})
+	defer be.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = be.Set(fmt.Sprintf("key%d", i), "value")
+	}
+	for i := 0; i < 100; i++ {
+		_, _ = be.Get(fmt.Sprintf("key%d", i))
+	}
+	_, _ = be.Get("key0")
+
+	setHeapRatio(be, 0.95)
+	be.checkPressure()
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 shed event, got %d", len(events))
+	}
+	if events[0].Evicted == 0 {
+		t.Error("expected entries to be evicted")
+	}
+	if got := be.Len(); got != 100-events[0].Evicted {
+		t.Errorf("expected %d entries after shedding, got %d", 100-events[0].Evicted, got)
+	}
+	if _, err := be.Get("key0"); err != nil {
+		t.Error("expected most recently used key to survive shedding")
+	}
+
+	stats := be.MemoryPressureStats()
+	if !stats.UnderPressure || stats.ShedEvents != 1 || stats.EvictedEntries != uint64(events[0].Evicted) {
+		t.Errorf("unexpected stats after shedding: %+v", stats)
+	}
+	if stats.Capacity >= stats.ConfiguredCapacity {
+		t.Errorf("expected effective capacity below %d, got %d", stats.ConfiguredCapacity, stats.Capacity)
+	}
+}
+
+func TestPressureCapacityFloorAndRecovery(t *testing.T) {
+	be := createPressureCache(t, nil)
+	defer be.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = be.Set(fmt.Sprintf("key%d", i), "value")
+	}
+
+	setHeapRatio(be, 0.99)
+	for i := 0; i < 20; i++ {
+		be.checkPressure()
+	}
+
+	stats := be.MemoryPressureStats()
+	if stats.Capacity != 28 {
+		t.Errorf("expected capacity to stop at the 25%% floor (28), got %d", stats.Capacity)
+	}
+	if be.Len() > stats.Capacity {
+		t.Errorf("expected at most %d entries, got %d", stats.Capacity, be.Len())
+	}
+
+	setHeapRatio(be, 0.8)
+	be.checkPressure()
+	if got := be.MemoryPressureStats().Capacity; got != 28 {
+		t.Errorf("expected capacity to hold between thresholds, got %d", got)
+	}
+
+	setHeapRatio(be, 0.1)
+	for i := 0; i < 20; i++ {
+		be.checkPressure()
+	}
+
+	stats = be.MemoryPressureStats()
+	if stats.Capacity != 100 || stats.UnderPressure {
+		t.Errorf("expected capacity restored to 100 without pressure, got %+v", stats)
+	}
+
+	for i := 0; i < 100; i++ {
+		_ = be.Set(fmt.Sprintf("key%d", i), "value")
+	}
+	if got := be.Len(); got != 100 {
+		t.Errorf("expected cache to hold 100 entries after recovery, got %d", got)
+	}
+}
+
+func TestPressureWaitsForGCBetweenSheds(t *testing.T) {
+	be := createPressureCache(t, nil)
+	defer be.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = be.Set(fmt.Sprintf("key%d", i), "value")
+	}
+
+	cycles := gcCycles.Add(1)
+	be.pressure.sample = func() mempressure.Sample {
+		return mempressure.Sample{HeapBytes: 990, Limit: 1000, GCCycles: cycles}
+	}
+	for i := 0; i < 5; i++ {
+		be.checkPressure()
+	}
+	if stats := be.MemoryPressureStats(); stats.ShedEvents != 1 {
+		t.Errorf("expected one shed until a GC completes, got %d", stats.ShedEvents)
+	}
+
+	cycles = gcCycles.Add(1)
+	be.checkPressure()
+	if stats := be.MemoryPressureStats(); stats.ShedEvents != 2 {
+		t.Errorf("expected another shed after a GC, got %d", stats.ShedEvents)
+	}
+}
+
+func TestPressureWatcherDisabledWithoutLimit(t *testing.T) {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:     4,
+				Capacity:       100,
+				MemoryPressure: &config.MemoryPressureConfig{},
+			},
+		},
+	}
+
+	cache, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	defer cache.Close()
+
+	if cache.(*inMemoryBackend[string]).pressure != nil {
+		t.Error("expected watcher to stay disabled without a limit")
+	}
+
+	stats := cache.(backend.MemoryPressureReporter).MemoryPressureStats()
+	if stats.Capacity != 100 || stats.ConfiguredCapacity != 100 {
+		t.Errorf("expected capacity 100, got %+v", stats)
+	}
+}
+
+func TestResizeAndReshardKeepShedCapacity(t *testing.T) {
+	be := createPressureCache(t, nil)
+	defer be.Close()
+
+	setHeapRatio(be, 0.99)
+	be.checkPressure()
+	shed := be.MemoryPressureStats().Capacity
+
+	if err := be.Resize(200); err != nil {
+		t.Fatalf("unexpected error resizing: %v", err)
+	}
+	stats := be.MemoryPressureStats()
+	if stats.ConfiguredCapacity != 200 || stats.Capacity != shed {
+		t.Errorf("expected Resize to keep the shed capacity %d of 200, got %+v", shed, stats)
+	}
+
+	if err := be.Reshard(8); err != nil {
+		t.Fatalf("unexpected error resharding: %v", err)
+	}
+	stats = be.MemoryPressureStats()
+	if stats.ConfiguredCapacity != 200 || stats.Capacity != shed {
+		t.Errorf("expected Reshard to keep the shed capacity %d of 200, got %+v", shed, stats)
+	}
+
+	setHeapRatio(be, 0.1)
+	for i := 0; i < 20; i++ {
+		be.checkPressure()
+	}
+	if got := be.MemoryPressureStats().Capacity; got != 200 {
+		t.Errorf("expected the watcher to restore capacity to 200, got %d", got)
+	}
+}
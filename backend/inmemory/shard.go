@@ -1,21 +1,32 @@
 package inmemory
 
 import (
-	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/halilbulentorhon/invacache-go/backend/option"
 	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
 )
 
+type shardItem[V any] struct {
+	expiresAt time.Time
+	value     V
+	key       string
+}
+
 type inMemoryShard[V any] struct {
-	items      map[string]*Entry[V]
-	head, tail *Entry[V]
-	mu         sync.RWMutex
-	count      int
-	capacity   int
-	defaultTTL time.Duration
+	items       map[string]*Entry[V]
+	head, tail  *Entry[V]
+	pool        *sync.Pool
+	clock       clock.Clock
+	mu          sync.RWMutex
+	count       int
+	capacity    int
+	maxCapacity int
+	defaultTTL  time.Duration
+	migrated    bool
 }
 
 func newInMemoryShard[V any](capacity int, defaultTTL time.Duration) inMemoryShard[V] {
@@ -25,11 +36,14 @@ func newInMemoryShard[V any](capacity int, defaultTTL time.Duration) inMemorySha
 	tail.prev = head
 
 	return inMemoryShard[V]{
-		items:      make(map[string]*Entry[V]),
-		head:       head,
-		tail:       tail,
-		capacity:   capacity,
-		defaultTTL: defaultTTL,
+		items:       make(map[string]*Entry[V]),
+		head:        head,
+		tail:        tail,
+		pool:        &sync.Pool{New: func() any { return new(Entry[V]) }},
+		clock:       clock.Real(),
+		capacity:    capacity,
+		maxCapacity: capacity,
+		defaultTTL:  defaultTTL,
 	}
 }
 
@@ -37,15 +51,13 @@ func (s *inMemoryShard[V]) get(key string) (V, error) {
 	entry, exists := s.items[key]
 	if !exists {
 		var zero V
-		return zero, fmt.Errorf("%s: %s", constant.ErrKeyNotFound, key)
+		return zero, constant.ErrNotFound
 	}
 
-	if entry.IsExpired() {
-		s.removeEntry(entry)
-		delete(s.items, key)
-		s.count--
+	if entry.expired(s.clock) {
+		s.dropEntry(entry)
 		var zero V
-		return zero, fmt.Errorf("%s: %s", constant.ErrKeyNotFound, key)
+		return zero, constant.ErrNotFound
 	}
 
 	s.moveToHead(entry)
@@ -53,7 +65,7 @@ func (s *inMemoryShard[V]) get(key string) (V, error) {
 }
 
 func (s *inMemoryShard[V]) set(key string, value V, options ...option.OptFnc) error {
-	cfg := option.ApplyOptions(options)
+	cfg := setConfig(options)
 
 	var expiresAt time.Time
 	if !cfg.NoExpiration {
@@ -62,68 +74,161 @@ func (s *inMemoryShard[V]) set(key string, value V, options ...option.OptFnc) er
 			ttl = s.defaultTTL
 		}
 		if ttl > 0 {
-			expiresAt = time.Now().Add(ttl)
+			expiresAt = s.clock.Now().Add(ttl)
 		}
 	}
 
+	s.insert(key, value, expiresAt)
+	return nil
+}
+
+func (s *inMemoryShard[V]) insert(key string, value V, expiresAt time.Time) *Entry[V] {
 	if existingEntry, exists := s.items[key]; exists {
 		existingEntry.Value = value
 		existingEntry.ExpiresAt = expiresAt
+		existingEntry.writeStamp = 0
 		s.moveToHead(existingEntry)
-		return nil
+		return existingEntry
 	}
 
-	newEntry := &Entry[V]{
-		Value:     value,
-		ExpiresAt: expiresAt,
-		Key:       key,
-	}
+	s.evictTo(s.capacity - 1)
 
-	for s.count >= s.capacity {
-		tailEntry := s.removeTail()
-		if tailEntry != nil {
-			delete(s.items, tailEntry.Key)
-			s.count--
-		}
-	}
+	newEntry := s.pool.Get().(*Entry[V])
+	newEntry.Value = value
+	newEntry.ExpiresAt = expiresAt
+	newEntry.Key = key
 
 	s.items[key] = newEntry
 	s.addToHead(newEntry)
 	s.count++
+	return newEntry
+}
 
-	return nil
+func (s *inMemoryShard[V]) stampWrite(key string, stamp int64) {
+	if entry, exists := s.items[key]; exists {
+		entry.writeStamp = stamp
+	}
+}
+
+// deleteIfOlder deletes key unless its live entry was written with a stamp
+// of at least stamp, and reports whether the entry is gone.
+func (s *inMemoryShard[V]) deleteIfOlder(key string, stamp int64) bool {
+	entry, exists := s.items[key]
+	if !exists {
+		return true
+	}
+	if entry.writeStamp >= stamp && !entry.expired(s.clock) {
+		return false
+	}
+	s.dropEntry(entry)
+	return true
 }
 
 func (s *inMemoryShard[V]) delete(key string) error {
 	if entry, exists := s.items[key]; exists {
-		s.removeEntry(entry)
-		delete(s.items, key)
-		s.count--
+		s.dropEntry(entry)
 	}
 
 	return nil
 }
 
+func (s *inMemoryShard[V]) deleteMatching(match func(key string) bool) {
+	for key, entry := range s.items {
+		if match(key) {
+			s.dropEntry(entry)
+		}
+	}
+}
+
+func (s *inMemoryShard[V]) dropEntry(entry *Entry[V]) {
+	s.removeEntry(entry)
+	delete(s.items, entry.Key)
+	s.count--
+	s.release(entry)
+}
+
+func (s *inMemoryShard[V]) release(entry *Entry[V]) {
+	*entry = Entry[V]{}
+	s.pool.Put(entry)
+}
+
 func (s *inMemoryShard[V]) sweepExpired() int {
 	var expiredKeys []string
 
 	for key, entry := range s.items {
-		if entry.IsExpired() {
+		if entry.expired(s.clock) {
 			expiredKeys = append(expiredKeys, key)
 		}
 	}
 
 	for _, key := range expiredKeys {
 		if entry := s.items[key]; entry != nil {
-			s.removeEntry(entry)
-			delete(s.items, key)
-			s.count--
+			s.dropEntry(entry)
 		}
 	}
 
 	return len(expiredKeys)
 }
 
+func (s *inMemoryShard[V]) shed(fraction, minFraction float64) int {
+	floor := max(int(math.Ceil(float64(s.maxCapacity)*minFraction)), 1)
+	step := int(math.Ceil(float64(s.maxCapacity) * fraction))
+	s.capacity = max(s.capacity-step, floor)
+
+	target := min(s.count-int(math.Ceil(float64(s.count)*fraction)), s.capacity)
+	return s.evictTo(target)
+}
+
+// resize sets the configured capacity. A shard shed under memory pressure
+// stays shed, within the new capacity, until the pressure watcher grows it.
+func (s *inMemoryShard[V]) resize(capacity int) int {
+	if s.capacity < s.maxCapacity {
+		s.capacity = min(s.capacity, capacity)
+	} else {
+		s.capacity = capacity
+	}
+	s.maxCapacity = capacity
+	return s.evictTo(s.capacity)
+}
+
+func (s *inMemoryShard[V]) evictTo(target int) int {
+	evicted := 0
+	for s.count > target {
+		tailEntry := s.tail.prev
+		if tailEntry == s.head {
+			break
+		}
+		s.dropEntry(tailEntry)
+		evicted++
+	}
+	return evicted
+}
+
+func (s *inMemoryShard[V]) grow(fraction float64) {
+	step := int(math.Ceil(float64(s.maxCapacity) * fraction))
+	s.capacity = min(s.capacity+step, s.maxCapacity)
+}
+
+func (s *inMemoryShard[V]) liveCount() int {
+	count := 0
+	for entry := s.head.next; entry != s.tail; entry = entry.next {
+		if !entry.expired(s.clock) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *inMemoryShard[V]) liveItems() []shardItem[V] {
+	items := make([]shardItem[V], 0, s.count)
+	for entry := s.head.next; entry != s.tail; entry = entry.next {
+		if !entry.expired(s.clock) {
+			items = append(items, shardItem[V]{key: entry.Key, value: entry.Value, expiresAt: entry.ExpiresAt})
+		}
+	}
+	return items
+}
+
 func (s *inMemoryShard[V]) addToHead(entry *Entry[V]) {
 	entry.prev = s.head
 	entry.next = s.head.next
@@ -7,6 +7,7 @@ import (
 
 	"github.com/halilbulentorhon/invacache-go/backend/option"
 	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock/clocktest"
 )
 
 func TestNewInMemoryShard(t *testing.T) {
@@ -38,7 +39,7 @@ func TestShardGetNonExistentKey(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for non-existent key")
 	}
-	if err.Error() != constant.ErrKeyNotFound+": nonexistent" {
+	if err != constant.ErrNotFound {
 		t.Errorf("expected 'key not found' error, got: %v", err)
 	}
 }
@@ -66,6 +67,7 @@ func TestShardSetAndGet(t *testing.T) {
 
 func TestShardSetWithTTL(t *testing.T) {
 	shard := newInMemoryShard[string](10, 0)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithTTL(100*time.Millisecond))
 	if err != nil {
@@ -80,7 +82,7 @@ func TestShardSetWithTTL(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	_, err = shard.get("key1")
 	if err == nil {
@@ -94,13 +96,14 @@ func TestShardSetWithTTL(t *testing.T) {
 
 func TestShardSetWithNoExpiration(t *testing.T) {
 	shard := newInMemoryShard[string](10, 0)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithNoExpiration())
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -243,6 +246,7 @@ func TestShardLRUBehavior(t *testing.T) {
 
 func TestShardSweepExpired(t *testing.T) {
 	shard := newInMemoryShard[string](10, 0)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithTTL(50*time.Millisecond))
 	if err != nil {
@@ -259,7 +263,7 @@ func TestShardSweepExpired(t *testing.T) {
 		t.Fatalf("unexpected error setting key3: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	expiredCount := shard.sweepExpired()
 	if expiredCount != 1 {
@@ -582,6 +586,7 @@ func TestShardClearResetsCapacity(t *testing.T) {
 
 func TestShardDefaultTTL(t *testing.T) {
 	shard := newInMemoryShard[string](10, 100*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1")
 	if err != nil {
@@ -596,7 +601,7 @@ func TestShardDefaultTTL(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	_, err = shard.get("key1")
 	if err == nil {
@@ -606,13 +611,14 @@ func TestShardDefaultTTL(t *testing.T) {
 
 func TestShardDefaultTTLOverriddenByOption(t *testing.T) {
 	shard := newInMemoryShard[string](10, 50*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithTTL(200*time.Millisecond))
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -622,7 +628,7 @@ func TestShardDefaultTTLOverriddenByOption(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(120 * time.Millisecond)
+	clk.Advance(120 * time.Millisecond)
 
 	_, err = shard.get("key1")
 	if err == nil {
@@ -632,13 +638,14 @@ func TestShardDefaultTTLOverriddenByOption(t *testing.T) {
 
 func TestShardDefaultTTLWithNoExpiration(t *testing.T) {
 	shard := newInMemoryShard[string](10, 50*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithNoExpiration())
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	clk.Advance(100 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -651,6 +658,7 @@ func TestShardDefaultTTLWithNoExpiration(t *testing.T) {
 
 func TestShardNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 	shard := newInMemoryShard[string](10, 100*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithNoExpiration())
 	if err != nil {
@@ -662,7 +670,7 @@ func TestShardNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 		t.Fatalf("unexpected error setting key2: %v", err)
 	}
 
-	time.Sleep(150 * time.Millisecond)
+	clk.Advance(150 * time.Millisecond)
 
 	value1, err := shard.get("key1")
 	if err != nil {
@@ -680,20 +688,21 @@ func TestShardNoExpirationFlagIgnoresDefaultTTL(t *testing.T) {
 
 func TestShardUpdateWithNoExpiration(t *testing.T) {
 	shard := newInMemoryShard[string](10, 50*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1")
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(30 * time.Millisecond)
+	clk.Advance(30 * time.Millisecond)
 
 	err = shard.set("key1", "value2", option.WithNoExpiration())
 	if err != nil {
 		t.Fatalf("unexpected error updating key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -706,13 +715,14 @@ func TestShardUpdateWithNoExpiration(t *testing.T) {
 
 func TestShardNoExpirationWithoutDefaultTTL(t *testing.T) {
 	shard := newInMemoryShard[string](10, 0)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1", option.WithNoExpiration())
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -725,13 +735,14 @@ func TestShardNoExpirationWithoutDefaultTTL(t *testing.T) {
 
 func TestShardDefaultTTLAppliedWhenNoOption(t *testing.T) {
 	shard := newInMemoryShard[string](10, 100*time.Millisecond)
+	clk := useFakeClock(&shard)
 
 	err := shard.set("key1", "value1")
 	if err != nil {
 		t.Fatalf("unexpected error setting key: %v", err)
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
 
 	value, err := shard.get("key1")
 	if err != nil {
@@ -741,10 +752,76 @@ func TestShardDefaultTTLAppliedWhenNoOption(t *testing.T) {
 		t.Errorf("expected 'value1', got '%s'", value)
 	}
 
-	time.Sleep(70 * time.Millisecond)
+	clk.Advance(70 * time.Millisecond)
 
 	_, err = shard.get("key1")
 	if err == nil {
 		t.Fatal("key should have expired with default TTL")
 	}
 }
+
+func TestShardLiveItemsSkipsExpired(t *testing.T) {
+	shard := newInMemoryShard[string](10, 0)
+	clk := useFakeClock(&shard)
+
+	_ = shard.set("key1", "value1")
+	_ = shard.set("key2", "value2", option.WithTTL(10*time.Millisecond))
+	_ = shard.set("key3", "value3")
+
+	clk.Advance(20 * time.Millisecond)
+
+	items := shard.liveItems()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 live items, got %d", len(items))
+	}
+	if items[0].key != "key3" || items[1].key != "key1" {
+		t.Errorf("expected most recently used first, got %s, %s", items[0].key, items[1].key)
+	}
+	if shard.liveCount() != 2 {
+		t.Errorf("expected live count 2, got %d", shard.liveCount())
+	}
+}
+
+func TestShardShedAndGrow(t *testing.T) {
+	shard := newInMemoryShard[int](10, 0)
+	for i := 0; i < 10; i++ {
+		_ = shard.set(string(rune('a'+i)), i)
+	}
+
+	if evicted := shard.shed(0.2, 0.5); evicted != 2 {
+		t.Errorf("expected 2 evictions, got %d", evicted)
+	}
+	if shard.capacity != 8 || shard.count != 8 {
+		t.Errorf("expected capacity and count 8, got %d and %d", shard.capacity, shard.count)
+	}
+	if _, err := shard.get("a"); err == nil {
+		t.Error("expected least recently used key to be shed")
+	}
+
+	for i := 0; i < 5; i++ {
+		shard.shed(0.2, 0.5)
+	}
+	if shard.capacity != 5 {
+		t.Errorf("expected capacity floor 5, got %d", shard.capacity)
+	}
+	if shard.count >= 5 {
+		t.Errorf("expected shedding to keep trimming entries below the floor, got %d", shard.count)
+	}
+
+	shard.grow(0.2)
+	if shard.capacity != 7 {
+		t.Errorf("expected capacity 7 after growing, got %d", shard.capacity)
+	}
+	for i := 0; i < 5; i++ {
+		shard.grow(0.2)
+	}
+	if shard.capacity != 10 {
+		t.Errorf("expected capacity capped at 10, got %d", shard.capacity)
+	}
+}
+
+func useFakeClock[V any](shard *inMemoryShard[V]) *clocktest.Fake {
+	clk := clocktest.NewFake(time.Unix(0, 0))
+	shard.clock = clk
+	return clk
+}
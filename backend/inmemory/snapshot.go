@@ -0,0 +1,221 @@
+package inmemory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+)
+
+const (
+	snapshotMagic   = "IVCS"
+	snapshotVersion = 1
+
+	snapshotEntryMarker = 1
+	snapshotEndMarker   = 0
+
+	// Restore rejects entries above these lengths instead of trusting the
+	// stream with the allocation size.
+	maxSnapshotKeyLength   = 64 << 10
+	maxSnapshotValueLength = 1 << 30
+)
+
+func (i *inMemoryBackend[V]) Snapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(snapshotVersion); err != nil {
+		return err
+	}
+
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+
+	written := 0
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.RLock()
+		items := shards[idx].liveItems()
+		shards[idx].mu.RUnlock()
+
+		// least recently used first, so a restore rebuilds the same LRU order
+		for j := len(items) - 1; j >= 0; j-- {
+			if err := i.writeSnapshotEntry(bw, items[j]); err != nil {
+				return err
+			}
+			written++
+		}
+	}
+
+	if err := bw.WriteByte(snapshotEndMarker); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	i.logger.Debug("cache snapshot written", "entries", written)
+	return nil
+}
+
+func (i *inMemoryBackend[V]) writeSnapshotEntry(w *bufio.Writer, item shardItem[V]) error {
+	data, err := i.codec.Marshal(item.value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for key %s: %w", item.key, err)
+	}
+	if len(item.key) > maxSnapshotKeyLength || len(data) > maxSnapshotValueLength {
+		return fmt.Errorf("entry for key %s is too large to snapshot", item.key)
+	}
+
+	var expiresAt int64
+	if !item.expiresAt.IsZero() {
+		expiresAt = item.expiresAt.UnixNano()
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	if err = w.WriteByte(snapshotEntryMarker); err != nil {
+		return err
+	}
+	if _, err = w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(item.key)))]); err != nil {
+		return err
+	}
+	if _, err = w.WriteString(item.key); err != nil {
+		return err
+	}
+	if _, err = w.Write(buf[:binary.PutVarint(buf[:], expiresAt)]); err != nil {
+		return err
+	}
+	if _, err = w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(data)))]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (i *inMemoryBackend[V]) Restore(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("invalid snapshot header")
+	}
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	restored, skipped := 0, 0
+	for {
+		marker, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot entry: %w", err)
+		}
+		if marker == snapshotEndMarker {
+			break
+		}
+		if marker != snapshotEntryMarker {
+			return fmt.Errorf("invalid snapshot entry marker %d", marker)
+		}
+
+		key, expiresAt, data, err := readSnapshotEntry(br)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot entry: %w", err)
+		}
+
+		opt := option.WithNoExpiration()
+		if expiresAt != 0 {
+			remaining := time.Unix(0, expiresAt).Sub(i.clock.Now())
+			if remaining <= 0 {
+				skipped++
+				continue
+			}
+			opt = option.WithTTL(remaining)
+		}
+
+		value, err := i.codec.Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode value for key %s: %w", key, err)
+		}
+
+		shard := i.lockShard(key)
+		err = shard.set(key, value, opt)
+		shard.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		restored++
+	}
+
+	i.logger.Info("cache snapshot restored", "restored", restored, "skipped_expired", skipped)
+	return nil
+}
+
+func readSnapshotEntry(r *bufio.Reader) (string, int64, []byte, error) {
+	keyLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if keyLen > maxSnapshotKeyLength {
+		return "", 0, nil, fmt.Errorf("snapshot key length %d exceeds the maximum of %d", keyLen, maxSnapshotKeyLength)
+	}
+	key := make([]byte, keyLen)
+	if _, err = io.ReadFull(r, key); err != nil {
+		return "", 0, nil, err
+	}
+	expiresAt, err := binary.ReadVarint(r)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	dataLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if dataLen > maxSnapshotValueLength {
+		return "", 0, nil, fmt.Errorf("snapshot value length %d exceeds the maximum of %d", dataLen, maxSnapshotValueLength)
+	}
+	data := make([]byte, dataLen)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return "", 0, nil, err
+	}
+	return string(key), expiresAt, data, nil
+}
+
+func (i *inMemoryBackend[V]) snapshotToFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = i.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (i *inMemoryBackend[V]) restoreFromFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		i.logger.Info("no snapshot found, starting empty", "path", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	return i.Restore(f)
+}
@@ -0,0 +1,195 @@
+package inmemory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+)
+
+type snapshotUser struct {
+	Name string
+	Age  int
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	source := createTestCache[snapshotUser](t)
+	defer source.Close()
+
+	_ = source.Set("user:1", snapshotUser{Name: "john", Age: 30})
+	_ = source.Set("user:2", snapshotUser{Name: "jane", Age: 25}, option.WithTTL(time.Hour))
+	_ = source.Set("user:3", snapshotUser{Name: "joe", Age: 40}, option.WithNoExpiration())
+
+	var buf bytes.Buffer
+	if err := source.(backend.Snapshotter).Snapshot(&buf); err != nil {
+		t.Fatalf("unexpected error writing snapshot: %v", err)
+	}
+
+	target := createTestCache[snapshotUser](t)
+	defer target.Close()
+
+	if err := target.(backend.Snapshotter).Restore(&buf); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %v", err)
+	}
+
+	if target.Len() != 3 {
+		t.Fatalf("expected 3 restored entries, got %d", target.Len())
+	}
+
+	user, err := target.Get("user:2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Name != "jane" || user.Age != 25 {
+		t.Errorf("unexpected restored value: %+v", user)
+	}
+
+	shard := target.(*inMemoryBackend[snapshotUser]).lockShard("user:2")
+	entry := shard.items["user:2"]
+	shard.mu.Unlock()
+	if entry.ExpiresAt.IsZero() || time.Until(entry.ExpiresAt) > time.Hour {
+		t.Errorf("expected expiration to be preserved, got %v", entry.ExpiresAt)
+	}
+}
+
+func TestRestoreSkipsExpiredEntries(t *testing.T) {
+	source, clk := createFakeClockCache[string](t)
+	defer source.Close()
+
+	_ = source.Set("short", "value", option.WithTTL(50*time.Millisecond))
+	_ = source.Set("long", "value", option.WithTTL(time.Hour))
+
+	var buf bytes.Buffer
+	if err := source.(backend.Snapshotter).Snapshot(&buf); err != nil {
+		t.Fatalf("unexpected error writing snapshot: %v", err)
+	}
+
+	clk.Advance(100 * time.Millisecond)
+
+	target := createTestCacheWithClock[string](t, clk)
+	defer target.Close()
+
+	if err := target.(backend.Snapshotter).Restore(&buf); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %v", err)
+	}
+
+	if _, err := target.Get("short"); err == nil {
+		t.Error("expired entry should not be restored")
+	}
+	if _, err := target.Get("long"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRestoreRespectsCapacity(t *testing.T) {
+	source := createTestCache[int](t)
+	defer source.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = source.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	var buf bytes.Buffer
+	if err := source.(backend.Snapshotter).Snapshot(&buf); err != nil {
+		t.Fatalf("unexpected error writing snapshot: %v", err)
+	}
+
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      2,
+				Capacity:        10,
+				SweeperInterval: time.Minute,
+			},
+		},
+	}
+	target, err := NewInMemoryBackend[int](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer target.Close()
+
+	if err = target.(backend.Snapshotter).Restore(&buf); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %v", err)
+	}
+
+	if target.Len() > 10 {
+		t.Errorf("expected at most 10 entries, got %d", target.Len())
+	}
+}
+
+func TestRestoreInvalidSnapshot(t *testing.T) {
+	cache := createTestCache[string](t)
+	defer cache.Close()
+
+	snapshotter := cache.(backend.Snapshotter)
+
+	if err := snapshotter.Restore(bytes.NewReader([]byte("nope!"))); err == nil {
+		t.Error("expected error for invalid header")
+	}
+	if err := snapshotter.Restore(bytes.NewReader([]byte{'I', 'V', 'C', 'S', 99})); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+	if err := snapshotter.Restore(bytes.NewReader([]byte{'I', 'V', 'C', 'S', 1, 1, 5})); err == nil {
+		t.Error("expected error for truncated entry")
+	}
+
+	huge := binary.AppendUvarint([]byte{'I', 'V', 'C', 'S', 1, 1}, 1<<62)
+	if err := snapshotter.Restore(bytes.NewReader(huge)); err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Errorf("expected error for oversized key length, got %v", err)
+	}
+	huge = binary.AppendVarint(append([]byte{'I', 'V', 'C', 'S', 1, 1, 1}, 'k'), 0)
+	huge = binary.AppendUvarint(huge, 1<<62)
+	if err := snapshotter.Restore(bytes.NewReader(huge)); err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Errorf("expected error for oversized value length, got %v", err)
+	}
+}
+
+func TestSnapshotPathOnCloseAndStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        100,
+				SweeperInterval: time.Minute,
+				SnapshotPath:    path,
+			},
+		},
+	}
+
+	first, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = first.Set("key1", "value1")
+	if err = first.Close(); err != nil {
+		t.Fatalf("unexpected error closing cache: %v", err)
+	}
+
+	if _, err = os.Stat(path); err != nil {
+		t.Fatalf("expected snapshot file to exist: %v", err)
+	}
+
+	second, err := NewInMemoryBackend[string](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer second.Close()
+
+	value, err := second.Get("key1")
+	if err != nil {
+		t.Fatalf("expected key1 to be restored: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("expected 'value1', got '%s'", value)
+	}
+}
@@ -0,0 +1,149 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+)
+
+type shardTable[V any] struct {
+	next   *shardTable[V]
+	cancel context.CancelFunc
+	shards []inMemoryShard[V]
+	mask   uint32
+}
+
+func newShardTable[V any](shardCount, capacity int, defaultTTL time.Duration, clk clock.Clock) *shardTable[V] {
+	shards := make([]inMemoryShard[V], shardCount)
+	for idx := range shards {
+		shards[idx] = newInMemoryShard[V](shardCapacity(capacity, shardCount, idx), defaultTTL)
+		shards[idx].clock = clk
+	}
+	return &shardTable[V]{
+		shards: shards,
+		mask:   uint32(shardCount - 1),
+	}
+}
+
+func shardCapacity(capacity, shardCount, idx int) int {
+	base := max(capacity/shardCount, 1)
+	if idx == shardCount-1 {
+		return base + max(capacity-base*shardCount, 0)
+	}
+	return base
+}
+
+func (i *inMemoryBackend[V]) startSweepers(table *shardTable[V]) {
+	ctx, cancel := context.WithCancel(i.ctx)
+	table.cancel = cancel
+	for idx := range table.shards {
+		i.wg.Add(1)
+		go i.runSweeper(ctx, &table.shards[idx], i.clock.NewTicker(i.sweeperInterval))
+	}
+}
+
+func (i *inMemoryBackend[V]) Resize(capacity int) error {
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+	if i.closed.Load() {
+		return constant.ErrClosed
+	}
+
+	shards := i.table.Load().shards
+	if capacity <= len(shards) {
+		return fmt.Errorf("capacity(%d) must be greater than shard count(%d)", capacity, len(shards))
+	}
+
+	evicted := 0
+	for idx := range shards {
+		shards[idx].mu.Lock()
+		evicted += shards[idx].resize(shardCapacity(capacity, len(shards), idx))
+		shards[idx].mu.Unlock()
+	}
+
+	i.logger.Info("cache resized", "capacity", capacity, "evicted", evicted)
+	return nil
+}
+
+func (i *inMemoryBackend[V]) SetDefaultTTL(ttl time.Duration) {
+	i.resizeMu.RLock()
+	defer i.resizeMu.RUnlock()
+
+	shards := i.table.Load().shards
+	for idx := range shards {
+		shards[idx].mu.Lock()
+		shards[idx].defaultTTL = ttl
+		shards[idx].mu.Unlock()
+	}
+}
+
+// Reshard migrates every live entry into a new table with shardCount shards
+// (rounded up to a power of two). Old shards are migrated one at a time, so a
+// reader or writer only waits for the shard its key lives in; operations on a
+// shard that has already been migrated are forwarded to the new table.
+func (i *inMemoryBackend[V]) Reshard(shardCount int) error {
+	i.resizeMu.Lock()
+	defer i.resizeMu.Unlock()
+	if i.closed.Load() {
+		return constant.ErrClosed
+	}
+
+	old := i.table.Load()
+	shardCount = nextPowerOfTwo(shardCount)
+	if shardCount == len(old.shards) {
+		return nil
+	}
+
+	capacity, effective := 0, 0
+	for idx := range old.shards {
+		old.shards[idx].mu.RLock()
+		capacity += old.shards[idx].maxCapacity
+		effective += old.shards[idx].capacity
+		old.shards[idx].mu.RUnlock()
+	}
+	if capacity <= shardCount {
+		return fmt.Errorf("shard count(%d) must be less than capacity(%d)", shardCount, capacity)
+	}
+
+	old.shards[0].mu.RLock()
+	defaultTTL := old.shards[0].defaultTTL
+	old.shards[0].mu.RUnlock()
+
+	next := newShardTable[V](shardCount, capacity, defaultTTL, i.clock)
+	if effective < capacity {
+		// Carry shedding by the memory-pressure watcher over to the new shards.
+		for idx := range next.shards {
+			next.shards[idx].capacity = min(shardCapacity(effective, shardCount, idx), next.shards[idx].maxCapacity)
+		}
+	}
+	old.next = next
+
+	migrated := 0
+	for idx := range old.shards {
+		shard := &old.shards[idx]
+		shard.mu.Lock()
+		for entry := shard.tail.prev; entry != shard.head; entry = entry.prev {
+			if entry.expired(i.clock) {
+				continue
+			}
+			target := &next.shards[hashKey(entry.Key)&next.mask]
+			target.mu.Lock()
+			target.insert(entry.Key, entry.Value, entry.ExpiresAt).writeStamp = entry.writeStamp
+			target.mu.Unlock()
+			migrated++
+		}
+		shard.migrated = true
+		shard.clear()
+		shard.mu.Unlock()
+	}
+
+	i.table.Store(next)
+	i.startSweepers(next)
+	old.cancel()
+
+	i.logger.Info("cache resharded", "shard_count", shardCount, "capacity", capacity, "migrated", migrated)
+	return nil
+}
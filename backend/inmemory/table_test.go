@@ -0,0 +1,265 @@
+package inmemory
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock/clocktest"
+)
+
+func createResizableCache(t *testing.T, capacity int) *inMemoryBackend[int] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      4,
+				Capacity:        capacity,
+				SweeperInterval: time.Minute,
+			},
+		},
+		Clock: clocktest.NewFake(time.Unix(0, 0)),
+	}
+
+	cache, err := NewInMemoryBackend[int](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	return cache.(*inMemoryBackend[int])
+}
+
+func TestShardCapacityDistribution(t *testing.T) {
+	total := 0
+	for idx := 0; idx < 4; idx++ {
+		total += shardCapacity(103, 4, idx)
+	}
+	if total != 103 {
+		t.Errorf("expected capacities to sum to 103, got %d", total)
+	}
+	if got := shardCapacity(103, 4, 3); got != 28 {
+		t.Errorf("expected remainder on the last shard (28), got %d", got)
+	}
+}
+
+func TestResizeShrinkEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := createResizableCache(t, 100)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	_, _ = cache.Get("key0")
+
+	if err := cache.Resize(40); err != nil {
+		t.Fatalf("unexpected error resizing: %v", err)
+	}
+	if got := cache.Len(); got > 40 {
+		t.Errorf("expected at most 40 entries, got %d", got)
+	}
+	if _, err := cache.Get("key0"); err != nil {
+		t.Error("expected most recently used key to survive the resize")
+	}
+	if stats := cache.MemoryPressureStats(); stats.ConfiguredCapacity != 40 {
+		t.Errorf("expected configured capacity 40, got %d", stats.ConfiguredCapacity)
+	}
+}
+
+func TestResizeGrow(t *testing.T) {
+	cache := createResizableCache(t, 100)
+	defer cache.Close()
+
+	var resizer backend.Resizer = cache
+	if err := resizer.Resize(200); err != nil {
+		t.Fatalf("unexpected error resizing: %v", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	if got := cache.Len(); got < 190 {
+		t.Errorf("expected the grown cache to hold close to 200 entries, got %d", got)
+	}
+}
+
+func TestResizeRejectsCapacityBelowShardCount(t *testing.T) {
+	cache := createResizableCache(t, 100)
+	defer cache.Close()
+
+	if err := cache.Resize(4); err == nil {
+		t.Error("expected error when capacity does not exceed shard count")
+	}
+}
+
+func TestSetDefaultTTL(t *testing.T) {
+	cache := createResizableCache(t, 100)
+	defer cache.Close()
+	clk := cache.clock.(*clocktest.Fake)
+
+	_ = cache.Set("before", 1)
+	cache.SetDefaultTTL(20 * time.Millisecond)
+	_ = cache.Set("after", 2)
+	_ = cache.Set("explicit", 3, option.WithTTL(time.Hour))
+
+	clk.Advance(40 * time.Millisecond)
+
+	if _, err := cache.Get("after"); err == nil {
+		t.Error("expected key written after SetDefaultTTL to expire")
+	}
+	if _, err := cache.Get("before"); err != nil {
+		t.Error("expected key written before SetDefaultTTL to keep its expiration")
+	}
+	if _, err := cache.Get("explicit"); err != nil {
+		t.Error("expected explicit TTL to override the default")
+	}
+}
+
+func TestReshardPreservesEntries(t *testing.T) {
+	cache := createResizableCache(t, 1000)
+	defer cache.Close()
+
+	for i := 0; i < 50; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i, option.WithTTL(time.Hour))
+	}
+	cache.SetDefaultTTL(time.Minute)
+
+	if err := cache.Reshard(16); err != nil {
+		t.Fatalf("unexpected error resharding: %v", err)
+	}
+
+	table := cache.table.Load()
+	if len(table.shards) != 16 {
+		t.Errorf("expected 16 shards, got %d", len(table.shards))
+	}
+	if got := cache.Len(); got != 50 {
+		t.Errorf("expected 50 entries after reshard, got %d", got)
+	}
+	for i := 0; i < 50; i++ {
+		if value, err := cache.Get(fmt.Sprintf("key%d", i)); err != nil || value != i {
+			t.Errorf("expected key%d=%d after reshard, got %d (%v)", i, i, value, err)
+		}
+	}
+
+	shard := cache.lockShard("key1")
+	expiresAt := shard.items["key1"].ExpiresAt
+	defaultTTL := shard.defaultTTL
+	shard.mu.Unlock()
+	if expiresAt.Sub(cache.clock.Now()) < 50*time.Minute {
+		t.Errorf("expected expiration to be preserved, got %v", expiresAt)
+	}
+	if defaultTTL != time.Minute {
+		t.Errorf("expected default TTL to carry over, got %v", defaultTTL)
+	}
+	if stats := cache.MemoryPressureStats(); stats.ConfiguredCapacity != 1000 {
+		t.Errorf("expected capacity 1000 after reshard, got %d", stats.ConfiguredCapacity)
+	}
+}
+
+func TestLenWaitsForReshard(t *testing.T) {
+	cache := createResizableCache(t, 1000)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	// A reader on the last shard pauses Reshard after the others have migrated.
+	old := cache.table.Load()
+	last := &old.shards[len(old.shards)-1]
+	last.mu.RLock()
+	resharded := make(chan error, 1)
+	go func() { resharded <- cache.Reshard(8) }()
+	for migrated := false; !migrated; {
+		time.Sleep(time.Millisecond)
+		old.shards[0].mu.RLock()
+		migrated = old.shards[0].migrated
+		old.shards[0].mu.RUnlock()
+	}
+
+	counted := make(chan int, 1)
+	keys := make(chan int, 1)
+	go func() { counted <- cache.Len() }()
+	go func() { keys <- len(cache.Keys()) }()
+	time.Sleep(10 * time.Millisecond)
+	last.mu.RUnlock()
+
+	if err := <-resharded; err != nil {
+		t.Fatalf("unexpected error resharding: %v", err)
+	}
+	if got := <-counted; got != 100 {
+		t.Errorf("expected Len to count 100 entries across a reshard, got %d", got)
+	}
+	if got := <-keys; got != 100 {
+		t.Errorf("expected Keys to list 100 keys across a reshard, got %d", got)
+	}
+}
+
+func TestReshardValidation(t *testing.T) {
+	cache := createResizableCache(t, 100)
+	defer cache.Close()
+
+	if err := cache.Reshard(3); err != nil {
+		t.Errorf("expected resharding to the current count to be a no-op, got %v", err)
+	}
+	if err := cache.Reshard(128); err == nil {
+		t.Error("expected error when shard count exceeds capacity")
+	}
+}
+
+func TestReshardUnderConcurrentAccess(t *testing.T) {
+	cache := createResizableCache(t, 100000)
+	defer cache.Close()
+
+	const writers = 8
+	stop := make(chan struct{})
+	last := make([]int, writers)
+	var wg, started sync.WaitGroup
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		started.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := fmt.Sprintf("w%d-%d", w, n)
+				_ = cache.Set(key, n)
+				if n == 0 {
+					started.Done()
+				}
+				if _, err := cache.Get(key); err != nil {
+					t.Errorf("expected %s to be readable right after set: %v", key, err)
+					return
+				}
+				_ = cache.Delete(fmt.Sprintf("w%d-%d", w, n-1))
+				last[w] = n
+			}
+		}(w)
+	}
+
+	started.Wait()
+	for _, count := range []int{16, 2, 64, 8} {
+		if err := cache.Reshard(count); err != nil {
+			t.Fatalf("unexpected error resharding to %d: %v", count, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+
+	for w := 0; w < writers; w++ {
+		key := fmt.Sprintf("w%d-%d", w, last[w])
+		if value, err := cache.Get(key); err != nil || value != last[w] {
+			t.Errorf("expected %s to survive resharding, got %d (%v)", key, value, err)
+		}
+	}
+	if got := cache.Len(); got != writers {
+		t.Errorf("expected exactly %d live keys, got %d", writers, got)
+	}
+}
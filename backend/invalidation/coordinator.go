@@ -0,0 +1,432 @@
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+var ErrSubscriptionClosed = errors.New("invalidation subscription closed before it was ready")
+
+type Coordinator struct {
+	ctx           context.Context
+	pubsub        PubSub
+	logger        logger.Logger
+	onGap         func()
+	onClear       func(namespace string, epoch uint64) error
+	deleteIfOlder func(key string, stamp int64) (bool, error)
+	queue         *publishQueue
+	dispatcher    *dispatcher
+	clock         *hybridClock
+	dedupe        *dedupeWindow
+	ready         chan struct{}
+	done          chan struct{}
+	nodeID        string
+	backoff       Backoff
+	queueConfig   QueueConfig
+	dispatch      DispatchConfig
+	dedupeWindow  time.Duration
+	closeFlush    time.Duration
+	readyOnce     sync.Once
+	envelope      bool
+	state         atomic.Int32
+	restarts      atomic.Int64
+	lastMessageAt atomic.Int64
+	sequence      atomic.Uint64
+	received      atomic.Int64
+	duplicates    atomic.Int64
+	stale         atomic.Int64
+	own           atomic.Int64
+}
+
+type Health struct {
+	LastMessageAt   time.Time       `json:"lastMessageAt"`
+	State           ConnectionState `json:"state"`
+	Publish         PublishStats    `json:"publish"`
+	Receive         ReceiveStats    `json:"receive"`
+	Restarts        int64           `json:"restarts"`
+	PublishFailures int64           `json:"publishFailures"`
+}
+
+func NewCoordinator(ctx context.Context, pubsub PubSub, log logger.Logger, opts ...CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		ctx:          ctx,
+		pubsub:       pubsub,
+		logger:       log,
+		ready:        make(chan struct{}),
+		done:         make(chan struct{}),
+		nodeID:       newNodeID(),
+		clock:        &hybridClock{now: time.Now},
+		dedupeWindow: constant.DefaultDedupeWindow,
+		closeFlush:   constant.DefaultCloseFlushTimeout,
+		backoff: Backoff{
+			Initial:    constant.DefaultResubscribeInitialBackoff,
+			Max:        constant.DefaultResubscribeMaxBackoff,
+			Multiplier: constant.DefaultResubscribeMultiplier,
+			Jitter:     constant.DefaultResubscribeJitter,
+		},
+		queueConfig: QueueConfig{
+			Overflow:   constant.DefaultPublishOverflow,
+			Size:       constant.DefaultPublishQueueSize,
+			MaxRetries: constant.DefaultPublishMaxRetries,
+			Backoff: Backoff{
+				Initial:    constant.DefaultPublishRetryBackoff,
+				Max:        constant.DefaultPublishMaxRetryBackoff,
+				Multiplier: constant.DefaultResubscribeMultiplier,
+				Jitter:     constant.DefaultResubscribeJitter,
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.queue = newPublishQueue(c.queueConfig)
+	c.dedupe = newDedupeWindow(c.dedupeWindow)
+	if c.dispatch.Workers > 0 {
+		c.dispatcher = newDispatcher(c.dispatch, log)
+	}
+	context.AfterFunc(ctx, c.queue.close)
+	c.state.Store(int32(StateConnecting))
+	return c
+}
+
+// Start runs the subscription and the publish worker in the background.
+func (c *Coordinator) Start(handler InvalidationHandler) {
+	go c.Run(handler)
+	go c.RunPublisher()
+}
+
+// Run subscribes and blocks until the coordinator's context is cancelled.
+// Whenever Subscribe returns before that, it is called again after a backoff
+// delay, and the gap handler runs first if one is set. With dispatch workers
+// configured, handler runs on them and Run also waits for them to exit.
+func (c *Coordinator) Run(handler InvalidationHandler) {
+	defer close(c.done)
+	defer c.state.Store(int32(StateClosed))
+
+	apply := func(message Message) error {
+		return c.apply(message, handler)
+	}
+	deliver := apply
+	if c.dispatcher != nil {
+		c.dispatcher.start(c.ctx, apply)
+		defer c.dispatcher.wait()
+		deliver = func(message Message) error {
+			return c.dispatcher.dispatch(c.ctx, message)
+		}
+	}
+
+	received := func(raw string) error {
+		c.lastMessageAt.Store(time.Now().UnixNano())
+		messages := DecodeMessages(raw)
+		c.logger.Debug("received invalidation", "keys", keysOf(messages))
+		var errs []error
+		for _, message := range messages {
+			if !c.accept(message) {
+				continue
+			}
+			if err := deliver(message); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	attempt := 0
+	for {
+		c.logger.Debug("starting invalidation subscription")
+		started := time.Now()
+		var err error
+		if rs, ok := c.pubsub.(ReadySubscriber); ok {
+			err = rs.SubscribeReady(c.ctx, received, c.markReady)
+		} else {
+			c.markUnconfirmed()
+			err = c.pubsub.Subscribe(c.ctx, received)
+		}
+		if c.ctx.Err() != nil {
+			c.logger.Info("invalidation subscription ended", "error", err)
+			return
+		}
+
+		c.state.Store(int32(StateReconnecting))
+		c.restarts.Add(1)
+		if time.Since(started) >= c.backoff.Max {
+			attempt = 0
+		}
+		delay := c.backoff.Delay(attempt)
+		attempt++
+		c.logger.Warn("invalidation subscription dropped, resubscribing", "error", err, "attempt", attempt, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if c.onGap != nil {
+			c.logger.Info("running gap handler after invalidation subscription drop")
+			c.onGap()
+		}
+	}
+}
+
+// accept drops messages this node sent and messages already seen within the
+// dedupe window, and moves the hybrid clock past the sender's stamp.
+func (c *Coordinator) accept(message Message) bool {
+	c.received.Add(1)
+	if message.Origin != "" && message.Origin == c.nodeID {
+		c.own.Add(1)
+		return false
+	}
+	if message.ID != "" && c.dedupe.seen(message.ID, time.Now()) {
+		c.duplicates.Add(1)
+		return false
+	}
+	if message.Stamp != 0 {
+		c.clock.observe(message.Stamp)
+	}
+	return true
+}
+
+// apply hands stamped invalidations to the delete-if-older handler when one
+// is set, so a delayed delete cannot remove a newer value; the ones it keeps
+// are counted as stale. Clears go to the clear handler when one is set.
+func (c *Coordinator) apply(message Message, handler InvalidationHandler) error {
+	if IsClearEvent(message.Key) && c.onClear != nil {
+		return c.onClear(message.Namespace, message.Epoch)
+	}
+	if message.Stamp != 0 && c.deleteIfOlder != nil && !IsClearEvent(message.Key) {
+		deleted, err := c.deleteIfOlder(message.Key, message.Stamp)
+		if err == nil && !deleted {
+			c.stale.Add(1)
+		}
+		return err
+	}
+	return handler(message.Key)
+}
+
+func (c *Coordinator) markReady() {
+	c.state.Store(int32(StateConnected))
+	c.readyOnce.Do(func() {
+		c.logger.Debug("invalidation subscription ready")
+		close(c.ready)
+	})
+}
+
+// markUnconfirmed releases WaitReady for a driver that cannot confirm its
+// subscription, without reporting it as connected.
+func (c *Coordinator) markUnconfirmed() {
+	c.state.Store(int32(StateUnconfirmed))
+	c.readyOnce.Do(func() {
+		c.logger.Warn("invalidation driver cannot confirm its subscription, invalidations sent while it starts may be missed")
+		close(c.ready)
+	})
+}
+
+// WaitReady blocks until the first subscription is established, or only
+// started for a driver without ReadySubscriber. A nil coordinator is always
+// ready.
+func (c *Coordinator) WaitReady(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	select {
+	case <-c.ready:
+		return nil
+	case <-c.done:
+		select {
+		case <-c.ready:
+			return nil
+		default:
+			return ErrSubscriptionClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Coordinator) Health() Health {
+	if c == nil {
+		return Health{State: StateDisabled}
+	}
+	publish := c.queue.snapshot()
+	receive := c.dispatcher.stats()
+	receive.Received = c.received.Load()
+	receive.Duplicates = c.duplicates.Load()
+	receive.Stale = c.stale.Load()
+	receive.Own = c.own.Load()
+	health := Health{
+		State:           ConnectionState(c.state.Load()),
+		Publish:         publish,
+		Receive:         receive,
+		Restarts:        c.restarts.Load(),
+		PublishFailures: publish.Undelivered(),
+	}
+	if at := c.lastMessageAt.Load(); at != 0 {
+		health.LastMessageAt = time.Unix(0, at)
+	}
+	return health
+}
+
+// State reports the subscription state; a nil coordinator is disabled.
+func (c *Coordinator) State() ConnectionState {
+	if c == nil {
+		return StateDisabled
+	}
+	return ConnectionState(c.state.Load())
+}
+
+// Restarts counts how many times the subscription was restarted.
+func (c *Coordinator) Restarts() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.restarts.Load()
+}
+
+// Stamp returns the stamp to record for a local write and pass to Publish.
+// It is zero unless envelopes are enabled; a nil coordinator returns zero.
+func (c *Coordinator) Stamp() int64 {
+	if c == nil || !c.envelope {
+		return 0
+	}
+	return c.clock.stamp()
+}
+
+// Publish queues message for the publish worker. A key that is already
+// waiting is coalesced. When the queue is full the overflow policy applies, so
+// Publish may block or return ErrQueueFull. message.Stamp is the write's
+// Stamp; ID and Origin are assigned when it is sent.
+func (c *Coordinator) Publish(message Message) error {
+	return c.queue.enqueue(message)
+}
+
+// RunPublisher publishes queued invalidations until the coordinator's context
+// is cancelled, retrying each failed publish with backoff.
+func (c *Coordinator) RunPublisher() {
+	for {
+		messages, ok := c.queue.next()
+		if !ok {
+			return
+		}
+		c.queue.done(len(messages), c.publishWithRetry(c.address(messages)) == nil)
+	}
+}
+
+// PublishSync publishes message before returning, bypassing the queue but
+// with the same retries, and returns the last error if every attempt failed.
+func (c *Coordinator) PublishSync(message Message) error {
+	err := c.publishWithRetry(c.address([]Message{message}))
+	c.queue.record(1, err == nil)
+	if err != nil {
+		return fmt.Errorf("failed to publish invalidation for key %s: %w", message.Key, err)
+	}
+	return nil
+}
+
+// address gives each message an ID and this node as its origin. Without
+// envelopes only the key and namespace are sent.
+func (c *Coordinator) address(messages []Message) []Message {
+	for idx := range messages {
+		if !c.envelope {
+			messages[idx] = Message{Key: messages[idx].Key, Namespace: messages[idx].Namespace}
+			continue
+		}
+		messages[idx].ID = fmt.Sprintf("%s-%d", c.nodeID, c.sequence.Add(1))
+		messages[idx].Origin = c.nodeID
+	}
+	return messages
+}
+
+func (c *Coordinator) publishWithRetry(messages []Message) error {
+	keys := keysOf(messages)
+	for attempt := 0; ; attempt++ {
+		err := c.publish(messages)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.queueConfig.MaxRetries || c.ctx.Err() != nil {
+			c.logger.Error("failed to publish invalidation", "keys", keys, "attempts", attempt+1, "error", err)
+			return err
+		}
+
+		delay := c.queueConfig.Backoff.Delay(attempt)
+		c.logger.Debug("retrying invalidation publish", "keys", keys, "attempt", attempt+1, "delay", delay, "error", err)
+		c.queue.retried()
+		if !retryDelay(c.ctx, delay) {
+			return err
+		}
+	}
+}
+
+func (c *Coordinator) publish(messages []Message) error {
+	if len(messages) == 1 {
+		return c.pubsub.Publish(c.ctx, EncodeMessage(messages[0]))
+	}
+	if bp, ok := c.pubsub.(BatchPublisher); ok {
+		encoded := make([]string, len(messages))
+		for idx, message := range messages {
+			encoded[idx] = EncodeMessage(message)
+		}
+		return bp.PublishBatch(c.ctx, encoded)
+	}
+	return c.pubsub.Publish(c.ctx, EncodeBatch(messages))
+}
+
+// Flush blocks until every queued invalidation has been published or given
+// up on. It returns ctx's error if ctx ends first, and ErrPublisherClosed if
+// the coordinator is cancelled with invalidations still queued. A nil
+// coordinator has nothing to flush.
+func (c *Coordinator) Flush(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	return c.queue.flush(ctx)
+}
+
+// FlushOnClose is Flush for a cache that is closing. Without a deadline on
+// ctx it gives up after constant.DefaultCloseFlushTimeout, so a bus that is
+// down cannot hold up shutdown while the queue retries.
+func (c *Coordinator) FlushOnClose(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.closeFlush)
+		defer cancel()
+	}
+	return c.queue.flush(ctx)
+}
+
+func (c *Coordinator) Close() error {
+	return c.pubsub.Close()
+}
+
+func IsClearEvent(key string) bool {
+	return key == constant.EmptyString
+}
+
+func keysOf(messages []Message) []string {
+	keys := make([]string, len(messages))
+	for idx, message := range messages {
+		keys[idx] = message.Key
+	}
+	return keys
+}
+
+func newNodeID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
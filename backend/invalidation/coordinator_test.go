@@ -0,0 +1,330 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+// flakyPubSub fails the first drops subscriptions, then blocks until ctx ends.
+type flakyPubSub struct {
+	drops      int
+	subscribes atomic.Int32
+	connected  chan struct{}
+	once       sync.Once
+}
+
+func (p *flakyPubSub) Publish(context.Context, string) error { return nil }
+
+func (p *flakyPubSub) Subscribe(ctx context.Context, _ InvalidationHandler) error {
+	if int(p.subscribes.Add(1)) <= p.drops {
+		return errors.New("connection reset")
+	}
+	p.once.Do(func() { close(p.connected) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *flakyPubSub) Close() error { return nil }
+
+func TestCoordinatorResubscribesAfterDrop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := &flakyPubSub{drops: 3, connected: make(chan struct{})}
+	var gaps atomic.Int32
+
+	c := NewCoordinator(ctx, pubsub, logger.Discard(),
+		WithBackoff(Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2, Jitter: 0.5}),
+		WithGapHandler(func() { gaps.Add(1) }),
+	)
+	if c.State() != StateConnecting {
+		t.Errorf("expected connecting before Run, got %v", c.State())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Run(func(string) error { return nil })
+		close(done)
+	}()
+
+	select {
+	case <-pubsub.connected:
+	case <-time.After(time.Second):
+		t.Fatal("expected the coordinator to resubscribe")
+	}
+	if c.State() != StateUnconfirmed {
+		t.Errorf("expected unconfirmed for a driver without SubscribeReady, got %v", c.State())
+	}
+	if c.Restarts() != 3 || gaps.Load() != 3 {
+		t.Errorf("expected 3 restarts and gap calls, got %d and %d", c.Restarts(), gaps.Load())
+	}
+
+	cancel()
+	<-done
+	if c.State() != StateClosed {
+		t.Errorf("expected closed after cancel, got %v", c.State())
+	}
+}
+
+func TestCoordinatorStopsDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := &flakyPubSub{drops: 1, connected: make(chan struct{})}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithBackoff(Backoff{Initial: time.Hour, Max: time.Hour, Multiplier: 2}))
+
+	done := make(chan struct{})
+	go func() {
+		c.Run(func(string) error { return nil })
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for c.State() != StateReconnecting {
+		if time.Now().After(deadline) {
+			t.Fatal("expected reconnecting state")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return when cancelled during backoff")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := b.Delay(attempt); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+	if got := b.Delay(10000); got != time.Second {
+		t.Errorf("expected huge attempts to be capped, got %v", got)
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := b.Delay(3); got < 400*time.Millisecond || got > 800*time.Millisecond {
+			t.Fatalf("expected jittered delay within [400ms, 800ms], got %v", got)
+		}
+	}
+}
+
+func TestNilCoordinatorState(t *testing.T) {
+	var c *Coordinator
+	if c.State() != StateDisabled || c.Restarts() != 0 {
+		t.Error("expected nil coordinator to report disabled")
+	}
+	if StateReconnecting.String() != "reconnecting" {
+		t.Errorf("unexpected state name %q", StateReconnecting.String())
+	}
+}
+
+type readyPubSub struct {
+	confirm  chan struct{}
+	messages chan string
+}
+
+func (p *readyPubSub) Publish(context.Context, string) error { return errors.New("bus unavailable") }
+
+func (p *readyPubSub) Subscribe(ctx context.Context, handler InvalidationHandler) error {
+	return p.SubscribeReady(ctx, handler, func() {})
+}
+
+func (p *readyPubSub) SubscribeReady(ctx context.Context, handler InvalidationHandler, ready func()) error {
+	select {
+	case <-p.confirm:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	ready()
+	for {
+		select {
+		case key := <-p.messages:
+			_ = handler(key)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *readyPubSub) Close() error { return nil }
+
+func TestCoordinatorWaitReadyAndHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &readyPubSub{confirm: make(chan struct{}), messages: make(chan string)}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithPublishQueue(QueueConfig{}))
+	received := make(chan string, 1)
+	c.Start(func(key string) error {
+		received <- key
+		return nil
+	})
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := c.WaitReady(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected WaitReady to time out before confirmation, got %v", err)
+	}
+	if state := c.Health().State; state != StateConnecting {
+		t.Errorf("expected connecting before confirmation, got %v", state)
+	}
+
+	close(pubsub.confirm)
+	if err := c.WaitReady(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pubsub.messages <- "user:1"
+	<-received
+	if err := c.Publish(Message{Key: "user:2"}); err != nil {
+		t.Fatalf("unexpected error queueing publish: %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	health := c.Health()
+	if health.State != StateConnected || health.PublishFailures != 1 || health.Publish.Failed != 1 || health.LastMessageAt.IsZero() {
+		t.Errorf("unexpected health %+v", health)
+	}
+}
+
+func TestCoordinatorWaitReadyAfterClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := &readyPubSub{confirm: make(chan struct{})}
+	c := NewCoordinator(ctx, pubsub, logger.Discard())
+	c.Start(func(string) error { return nil })
+
+	cancel()
+	if err := c.WaitReady(context.Background()); !errors.Is(err, ErrSubscriptionClosed) {
+		t.Errorf("expected ErrSubscriptionClosed, got %v", err)
+	}
+
+	var disabled *Coordinator
+	if err := disabled.WaitReady(context.Background()); err != nil || disabled.Health().State != StateDisabled {
+		t.Error("expected a nil coordinator to be ready and disabled")
+	}
+}
+
+func TestCoordinatorUnpacksBatchMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &readyPubSub{confirm: make(chan struct{}), messages: make(chan string)}
+	close(pubsub.confirm)
+	c := NewCoordinator(ctx, pubsub, logger.Discard())
+	received := make(chan string, 3)
+	c.Start(func(key string) error {
+		received <- key
+		return nil
+	})
+
+	pubsub.messages <- EncodeBatch([]Message{{Key: "user:1"}, {Key: "user:2"}})
+	pubsub.messages <- "user:3"
+	for _, want := range []string{"user:1", "user:2", "user:3"} {
+		if got := <-received; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestCoordinatorDropsOwnDuplicateAndStaleMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &readyPubSub{confirm: make(chan struct{}), messages: make(chan string)}
+	close(pubsub.confirm)
+	received := make(chan string, 4)
+	deleteIfOlder := func(key string, stamp int64) (bool, error) {
+		if key == "user:1" && stamp <= 100 {
+			return false, nil
+		}
+		received <- key
+		return true, nil
+	}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithEnvelope(time.Minute), WithDeleteIfOlder(deleteIfOlder))
+	c.Start(func(key string) error {
+		received <- key
+		return nil
+	})
+
+	pubsub.messages <- EncodeMessage(Message{ID: c.nodeID + "-1", Origin: c.nodeID, Key: "own"})
+	pubsub.messages <- EncodeMessage(Message{ID: "peer-1", Origin: "peer", Key: "user:1", Stamp: 50})
+	pubsub.messages <- EncodeMessage(Message{ID: "peer-2", Origin: "peer", Key: "user:1", Stamp: 150})
+	pubsub.messages <- EncodeMessage(Message{ID: "peer-2", Origin: "peer", Key: "user:1", Stamp: 150})
+	pubsub.messages <- "user:2"
+	for _, want := range []string{"user:1", "user:2"} {
+		if got := <-received; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+
+	receive := c.Health().Receive
+	if receive.Received != 5 || receive.Own != 1 || receive.Stale != 1 || receive.Duplicates != 1 {
+		t.Errorf("unexpected receive stats %+v", receive)
+	}
+	if stamp := c.Stamp(); stamp <= 150 {
+		t.Errorf("expected local stamps to move past observed ones, got %d", stamp)
+	}
+}
+
+func TestCoordinatorEnvelopeAddressesMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &gatedPubSub{}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithEnvelope(time.Minute))
+
+	stamp := c.Stamp()
+	if err := c.PublishSync(Message{Key: "user:1", Stamp: stamp}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	messages := DecodeMessages(pubsub.keys()[0])
+	if len(messages) != 1 || messages[0].Key != "user:1" || messages[0].Stamp != stamp ||
+		messages[0].Origin != c.nodeID || messages[0].ID == "" {
+		t.Errorf("unexpected message %+v", messages)
+	}
+
+	plain := NewCoordinator(ctx, &gatedPubSub{}, logger.Discard())
+	if plain.Stamp() != 0 {
+		t.Error("expected no stamps without an envelope")
+	}
+}
+
+func TestCoordinatorRoutesClearsToClearHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &readyPubSub{confirm: make(chan struct{}), messages: make(chan string)}
+	close(pubsub.confirm)
+	type clear struct {
+		namespace string
+		epoch     uint64
+	}
+	clears := make(chan clear, 2)
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithClearHandler(func(namespace string, epoch uint64) error {
+		clears <- clear{namespace, epoch}
+		return nil
+	}))
+	keys := make(chan string, 1)
+	c.Start(func(key string) error {
+		keys <- key
+		return nil
+	})
+
+	pubsub.messages <- ""
+	pubsub.messages <- EncodeMessage(Message{Namespace: "user", Epoch: 7})
+	pubsub.messages <- "user:1"
+	if got := <-clears; got != (clear{}) {
+		t.Errorf("expected a full clear, got %+v", got)
+	}
+	if got := <-clears; got != (clear{"user", 7}) {
+		t.Errorf("expected a clear of namespace user at epoch 7, got %+v", got)
+	}
+	if got := <-keys; got != "user:1" {
+		t.Errorf("expected keys to reach the invalidation handler, got %q", got)
+	}
+}
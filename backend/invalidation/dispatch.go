@@ -0,0 +1,173 @@
+package invalidation
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+// DispatchConfig moves received invalidations off the subscription onto
+// Workers goroutines. Keys are partitioned by hash, so invalidations for the
+// same key run in the order they were received. Each worker buffers up to
+// QueueSize/Workers messages; when its buffer is full the subscription waits.
+type DispatchConfig struct {
+	Workers   int
+	QueueSize int
+}
+
+// ReceiveStats describes how received invalidations are being processed.
+// Lag is the time a message waited between arriving and its handler starting.
+// Duplicates, Stale and Own count messages that were dropped because they
+// were redelivered, older than the local write, or sent by this node.
+type ReceiveStats struct {
+	Depth         int           `json:"depth"`
+	Received      int64         `json:"received"`
+	Handled       int64         `json:"handled"`
+	HandlerErrors int64         `json:"handlerErrors"`
+	Duplicates    int64         `json:"duplicates"`
+	Stale         int64         `json:"stale"`
+	Own           int64         `json:"own"`
+	LastLag       time.Duration `json:"lastLag"`
+	MaxLag        time.Duration `json:"maxLag"`
+}
+
+type dispatchItem struct {
+	receivedAt time.Time
+	barrier    *clearBarrier
+	message    Message
+}
+
+// clearBarrier makes a clear event wait until every partition has reached
+// it, so no invalidation received before the clear runs after it, and none
+// received after it runs before it.
+type clearBarrier struct {
+	done    chan struct{}
+	pending atomic.Int32
+}
+
+type dispatcher struct {
+	logger        logger.Logger
+	handler       func(Message) error
+	partitions    []chan dispatchItem
+	wg            sync.WaitGroup
+	handled       atomic.Int64
+	handlerErrors atomic.Int64
+	lastLag       atomic.Int64
+	maxLag        atomic.Int64
+}
+
+func newDispatcher(cfg DispatchConfig, log logger.Logger) *dispatcher {
+	d := &dispatcher{logger: log, partitions: make([]chan dispatchItem, cfg.Workers)}
+	size := max(cfg.QueueSize/cfg.Workers, 1)
+	for idx := range d.partitions {
+		d.partitions[idx] = make(chan dispatchItem, size)
+	}
+	return d
+}
+
+func (d *dispatcher) start(ctx context.Context, handler func(Message) error) {
+	d.handler = handler
+	for _, partition := range d.partitions {
+		d.wg.Add(1)
+		go d.work(ctx, partition)
+	}
+}
+
+func (d *dispatcher) wait() {
+	d.wg.Wait()
+}
+
+// dispatch hands message to its key's worker. It only blocks when that
+// worker's buffer is full, and gives up when ctx ends.
+func (d *dispatcher) dispatch(ctx context.Context, message Message) error {
+	item := dispatchItem{message: message, receivedAt: time.Now()}
+	if IsClearEvent(message.Key) {
+		item.barrier = &clearBarrier{done: make(chan struct{})}
+		item.barrier.pending.Store(int32(len(d.partitions)))
+		for _, partition := range d.partitions {
+			if err := send(ctx, partition, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return send(ctx, d.partitions[partitionOf(message.Key, len(d.partitions))], item)
+}
+
+func send(ctx context.Context, partition chan<- dispatchItem, item dispatchItem) error {
+	select {
+	case partition <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dispatcher) work(ctx context.Context, partition <-chan dispatchItem) {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-partition:
+			if item.barrier == nil {
+				d.handle(item)
+				continue
+			}
+			if item.barrier.pending.Add(-1) == 0 {
+				d.handle(item)
+				close(item.barrier.done)
+				continue
+			}
+			select {
+			case <-item.barrier.done:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (d *dispatcher) handle(item dispatchItem) {
+	lag := time.Since(item.receivedAt)
+	d.lastLag.Store(int64(lag))
+	for {
+		current := d.maxLag.Load()
+		if int64(lag) <= current || d.maxLag.CompareAndSwap(current, int64(lag)) {
+			break
+		}
+	}
+
+	if err := d.handler(item.message); err != nil {
+		d.handlerErrors.Add(1)
+		d.logger.Error("failed to process invalidation", "key", item.message.Key, "error", err)
+	}
+	d.handled.Add(1)
+}
+
+func (d *dispatcher) stats() ReceiveStats {
+	if d == nil {
+		return ReceiveStats{}
+	}
+	depth := 0
+	for _, partition := range d.partitions {
+		depth += len(partition)
+	}
+	return ReceiveStats{
+		Depth:         depth,
+		Handled:       d.handled.Load(),
+		HandlerErrors: d.handlerErrors.Load(),
+		LastLag:       time.Duration(d.lastLag.Load()),
+		MaxLag:        time.Duration(d.maxLag.Load()),
+	}
+}
+
+func partitionOf(key string, partitions int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
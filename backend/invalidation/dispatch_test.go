@@ -0,0 +1,135 @@
+package invalidation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+// keyInOtherPartition finds a key that is not handled by key's worker.
+func keyInOtherPartition(key string, partitions int) string {
+	for i := 0; ; i++ {
+		other := fmt.Sprintf("other:%d", i)
+		if partitionOf(other, partitions) != partitionOf(key, partitions) {
+			return other
+		}
+	}
+}
+
+func TestDispatcherSlowKeyDoesNotStallOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release := make(chan struct{})
+	handled := make(chan string, 4)
+	d := newDispatcher(DispatchConfig{Workers: 4, QueueSize: 16}, logger.Discard())
+	d.start(ctx, func(message Message) error {
+		key := message.Key
+		if key == "slow" {
+			<-release
+		}
+		handled <- key
+		return nil
+	})
+
+	fast := keyInOtherPartition("slow", 4)
+	_ = d.dispatch(ctx, Message{Key: "slow"})
+	_ = d.dispatch(ctx, Message{Key: fast})
+
+	select {
+	case key := <-handled:
+		if key != fast {
+			t.Errorf("expected %s to be handled first, got %s", fast, key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the fast key to be handled while the slow one blocks")
+	}
+	close(release)
+	if key := <-handled; key != "slow" {
+		t.Errorf("expected slow next, got %s", key)
+	}
+	cancel()
+	d.wait()
+}
+
+func TestDispatcherKeepsPerKeyOrderAndClearBarrier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var order []string
+	d := newDispatcher(DispatchConfig{Workers: 4, QueueSize: 64}, logger.Discard())
+	d.start(ctx, func(message Message) error {
+		mu.Lock()
+		order = append(order, message.Key)
+		mu.Unlock()
+		return nil
+	})
+
+	before := []string{"a", "b", "c", "a"}
+	after := []string{"d", "a"}
+	for _, key := range before {
+		_ = d.dispatch(ctx, Message{Key: key})
+	}
+	_ = d.dispatch(ctx, Message{})
+	for _, key := range after {
+		_ = d.dispatch(ctx, Message{Key: key})
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for d.stats().Handled < 7 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 7 {
+		t.Fatalf("expected 7 handled invalidations, got %v", order)
+	}
+	clearAt := -1
+	for idx, key := range order {
+		if IsClearEvent(key) {
+			clearAt = idx
+		}
+	}
+	if clearAt != len(before) {
+		t.Errorf("expected the clear to run after every earlier key and before every later one, got %q", order)
+	}
+
+	stats := d.stats()
+	if stats.Handled != 7 || stats.HandlerErrors != 0 || stats.MaxLag < stats.LastLag {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCoordinatorDispatchesToWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := &readyPubSub{confirm: make(chan struct{}), messages: make(chan string)}
+	close(pubsub.confirm)
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithDispatch(DispatchConfig{Workers: 2, QueueSize: 8}))
+
+	handled := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		c.Run(func(key string) error {
+			handled <- key
+			return fmt.Errorf("handler failed")
+		})
+		close(done)
+	}()
+
+	pubsub.messages <- "user:1"
+	if key := <-handled; key != "user:1" {
+		t.Errorf("expected user:1, got %s", key)
+	}
+	cancel()
+	<-done
+
+	if receive := c.Health().Receive; receive.Received != 1 || receive.Handled != 1 || receive.HandlerErrors != 1 {
+		t.Errorf("unexpected receive stats %+v", receive)
+	}
+}
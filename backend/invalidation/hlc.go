@@ -0,0 +1,67 @@
+package invalidation
+
+import (
+	"sync"
+	"time"
+)
+
+// hybridClock is a hybrid logical clock. A stamp holds wall time in
+// milliseconds in its upper bits and a counter in its lower 16 bits. Stamps
+// only move forward, and observing a peer's stamp moves the clock past it, so
+// a write made after receiving an invalidation always stamps later than it,
+// even when the peer's wall clock runs ahead.
+type hybridClock struct {
+	now  func() time.Time
+	mu   sync.Mutex
+	last int64
+}
+
+func (h *hybridClock) stamp() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if wall := h.now().UnixMilli() << 16; wall > h.last {
+		h.last = wall
+	} else {
+		h.last++
+	}
+	return h.last
+}
+
+func (h *hybridClock) observe(stamp int64) {
+	h.mu.Lock()
+	if stamp > h.last {
+		h.last = stamp
+	}
+	h.mu.Unlock()
+}
+
+// dedupeWindow remembers message IDs for window so redelivered messages can
+// be dropped.
+type dedupeWindow struct {
+	seenAt map[string]time.Time
+	order  []string
+	window time.Duration
+	mu     sync.Mutex
+}
+
+func newDedupeWindow(window time.Duration) *dedupeWindow {
+	return &dedupeWindow{seenAt: make(map[string]time.Time), window: window}
+}
+
+// seen records id and reports whether it was already recorded within the
+// window.
+func (d *dedupeWindow) seen(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.order) > 0 && now.Sub(d.seenAt[d.order[0]]) > d.window {
+		delete(d.seenAt, d.order[0])
+		d.order = d.order[1:]
+	}
+	if _, ok := d.seenAt[id]; ok {
+		return true
+	}
+	d.seenAt[id] = now
+	d.order = append(d.order, id)
+	return false
+}
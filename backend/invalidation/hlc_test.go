@@ -0,0 +1,60 @@
+package invalidation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHybridClockMovesForward(t *testing.T) {
+	wall := time.UnixMilli(1_000)
+	clock := &hybridClock{now: func() time.Time { return wall }}
+
+	first := clock.stamp()
+	second := clock.stamp()
+	if second <= first {
+		t.Errorf("expected stamps to increase on the same millisecond, got %d then %d", first, second)
+	}
+
+	peer := time.UnixMilli(5_000).UnixMilli() << 16
+	clock.observe(peer)
+	if stamp := clock.stamp(); stamp <= peer {
+		t.Errorf("expected a stamp after observing %d, got %d", peer, stamp)
+	}
+
+	wall = time.UnixMilli(500)
+	if stamp := clock.stamp(); stamp <= peer {
+		t.Errorf("expected the clock not to go back with the wall clock, got %d", stamp)
+	}
+}
+
+func TestDedupeWindow(t *testing.T) {
+	d := newDedupeWindow(time.Minute)
+	now := time.Now()
+
+	if d.seen("a", now) {
+		t.Error("expected the first sighting not to be a duplicate")
+	}
+	if !d.seen("a", now.Add(30*time.Second)) {
+		t.Error("expected a redelivery within the window to be a duplicate")
+	}
+	if d.seen("a", now.Add(2*time.Minute)) {
+		t.Error("expected the ID to be forgotten after the window")
+	}
+}
+
+func TestMessageEncoding(t *testing.T) {
+	if raw := EncodeMessage(Message{Key: "user:1"}); raw != "user:1" {
+		t.Errorf("expected a message without envelope fields to encode as its key, got %q", raw)
+	}
+
+	message := Message{ID: "n-1", Origin: "n", Key: "user:1", Stamp: 42}
+	decoded := DecodeMessages(EncodeMessage(message))
+	if len(decoded) != 1 || decoded[0] != message {
+		t.Errorf("expected %+v back, got %+v", message, decoded)
+	}
+
+	batch := DecodeMessages(EncodeBatch([]Message{message, {Key: ""}}))
+	if len(batch) != 2 || batch[0] != message || !IsClearEvent(batch[1].Key) {
+		t.Errorf("unexpected batch %+v", batch)
+	}
+}
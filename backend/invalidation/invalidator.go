@@ -3,6 +3,9 @@ package invalidation
 import (
 	"context"
 	"fmt"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/decode"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
 )
 
 type PubSub interface {
@@ -13,18 +16,102 @@ type PubSub interface {
 
 type InvalidationHandler func(key string) error
 
+// ReadySubscriber is implemented by drivers that can confirm a subscription
+// is established. ready must be called once the driver is receiving, before
+// any handler call. For drivers without it, WaitReady returns as soon as
+// Subscribe is called and the state is StateUnconfirmed.
+type ReadySubscriber interface {
+	SubscribeReady(ctx context.Context, handler InvalidationHandler, ready func()) error
+}
+
+// BatchPublisher is implemented by drivers with their own framing for
+// sending several invalidations as one bus message. The driver's subscriber
+// must unpack that message and call the handler once per key. Drivers without
+// it receive each batch as a single message built by EncodeBatch.
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, keys []string) error
+}
+
 type InvalidatorFactory func(config interface{}) (PubSub, error)
 
-var factories = make(map[string]InvalidatorFactory)
+// LoggingInvalidatorFactory is an InvalidatorFactory that also receives the
+// cache's logger scoped to the driver; drivers should log through it.
+type LoggingInvalidatorFactory func(config interface{}, log logger.Logger) (PubSub, error)
+
+type ConfigValidator func(config map[string]any) error
+
+var (
+	factories  = make(map[string]LoggingInvalidatorFactory)
+	validators = make(map[string]ConfigValidator)
+)
 
 func RegisterInvalidator(name string, factory InvalidatorFactory) {
+	RegisterInvalidatorWithLogger(name, func(config interface{}, _ logger.Logger) (PubSub, error) {
+		return factory(config)
+	})
+}
+
+func RegisterInvalidatorWithLogger(name string, factory LoggingInvalidatorFactory) {
 	factories[name] = factory
 }
 
+// RegisterTypedInvalidator registers a driver whose DriverConfig is decoded
+// into C before factory runs. Numbers and strings are coerced to the field
+// types, durations accept "30s", unknown keys are rejected and fields tagged
+// required:"true" must be set. The same decoding backs config validation.
+func RegisterTypedInvalidator[C any](name string, factory func(cfg C) (PubSub, error)) {
+	RegisterTypedInvalidatorWithLogger(name, func(cfg C, _ logger.Logger) (PubSub, error) {
+		return factory(cfg)
+	})
+}
+
+// RegisterTypedInvalidatorWithLogger is RegisterTypedInvalidator for drivers
+// that log through the cache's logger.
+func RegisterTypedInvalidatorWithLogger[C any](name string, factory func(cfg C, log logger.Logger) (PubSub, error)) {
+	RegisterInvalidatorWithLogger(name, func(config interface{}, log logger.Logger) (PubSub, error) {
+		cfg, err := decode.Config[C](config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s driver config: %w", name, err)
+		}
+		return factory(cfg, log)
+	})
+	RegisterConfigValidator(name, func(config map[string]any) error {
+		_, err := decode.Config[C](config)
+		return err
+	})
+}
+
+// RegisterConfigValidator lets a driver check its driver config without
+// connecting, so configuration errors surface from config validation.
+func RegisterConfigValidator(name string, validator ConfigValidator) {
+	validators[name] = validator
+}
+
+func IsRegistered(name string) bool {
+	_, exists := factories[name]
+	return exists
+}
+
+func ValidateConfig(invalidatorType string, config map[string]any) error {
+	if validator, exists := validators[invalidatorType]; exists {
+		return validator(config)
+	}
+	return nil
+}
+
 func NewInvalidator(invalidatorType string, config interface{}) (PubSub, error) {
+	return NewInvalidatorWithLogger(invalidatorType, config, nil)
+}
+
+// NewInvalidatorWithLogger builds a driver that logs through log, or
+// discards its logs when log is nil.
+func NewInvalidatorWithLogger(invalidatorType string, config interface{}, log logger.Logger) (PubSub, error) {
 	factory, exists := factories[invalidatorType]
 	if !exists {
 		return nil, fmt.Errorf("unknown invalidator type: %s", invalidatorType)
 	}
-	return factory(config)
+	if log == nil {
+		log = logger.Discard()
+	}
+	return factory(config, log)
 }
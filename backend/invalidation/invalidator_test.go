@@ -0,0 +1,105 @@
+package invalidation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type typedTestConfig struct {
+	Address string        `json:"address" required:"true"`
+	Timeout time.Duration `json:"timeout"`
+	DB      int           `json:"db"`
+}
+
+type typedTestPubSub struct {
+	cfg typedTestConfig
+	log logger.Logger
+}
+
+func (p *typedTestPubSub) Publish(context.Context, string) error { return nil }
+
+func (p *typedTestPubSub) Subscribe(context.Context, InvalidationHandler) error { return nil }
+
+func (p *typedTestPubSub) Close() error { return nil }
+
+func init() {
+	RegisterTypedInvalidatorWithLogger("typed-test", func(cfg typedTestConfig, log logger.Logger) (PubSub, error) {
+		return &typedTestPubSub{cfg: cfg, log: log}, nil
+	})
+}
+
+func TestRegisterTypedInvalidatorDecodesConfig(t *testing.T) {
+	pubsub, err := NewInvalidator("typed-test", map[string]any{
+		"Address": "localhost:6379",
+		"DB":      float64(3),
+		"Timeout": "250ms",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := pubsub.(*typedTestPubSub).cfg
+	if cfg.Address != "localhost:6379" || cfg.DB != 3 || cfg.Timeout != 250*time.Millisecond {
+		t.Errorf("unexpected decoded config: %+v", cfg)
+	}
+}
+
+func TestRegisterTypedInvalidatorRejectsBadConfig(t *testing.T) {
+	_, err := NewInvalidator("typed-test", map[string]any{"Adress": "localhost"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"invalid typed-test driver config", `unknown field "Adress"`, "address is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+
+	if err := ValidateConfig("typed-test", map[string]any{"Address": "x", "DB": "two"}); err == nil || !strings.Contains(err.Error(), `db: invalid integer "two"`) {
+		t.Errorf("expected validator to report coercion error, got %v", err)
+	}
+	if !IsRegistered("typed-test") || IsRegistered("missing") {
+		t.Error("unexpected registration state")
+	}
+}
+
+func TestNewInvalidatorPassesLogger(t *testing.T) {
+	log := logger.Discard()
+	pubsub, err := NewInvalidatorWithLogger("typed-test", map[string]any{"Address": "x"}, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pubsub.(*typedTestPubSub).log != log {
+		t.Error("expected factory to receive the given logger")
+	}
+
+	pubsub, err = NewInvalidatorWithLogger("typed-test", map[string]any{"Address": "x"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pubsub.(*typedTestPubSub).log == nil {
+		t.Error("expected a non-nil logger when none is given")
+	}
+}
+
+func TestRegisterWithoutLogger(t *testing.T) {
+	RegisterInvalidator("plain-test", func(config interface{}) (PubSub, error) {
+		return &typedTestPubSub{cfg: typedTestConfig{Address: config.(string)}}, nil
+	})
+	RegisterTypedInvalidator("plain-typed-test", func(cfg typedTestConfig) (PubSub, error) {
+		return &typedTestPubSub{cfg: cfg}, nil
+	})
+
+	pubsub, err := NewInvalidatorWithLogger("plain-test", "x", logger.Discard())
+	if err != nil || pubsub.(*typedTestPubSub).cfg.Address != "x" {
+		t.Errorf("expected a factory without a logger to be built, got %v, %v", pubsub, err)
+	}
+	pubsub, err = NewInvalidator("plain-typed-test", map[string]any{"Address": "y"})
+	if err != nil || pubsub.(*typedTestPubSub).cfg.Address != "y" {
+		t.Errorf("expected a typed factory without a logger to be built, got %v, %v", pubsub, err)
+	}
+}
@@ -0,0 +1,60 @@
+package invalidation
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Message is one invalidation on the wire. Messages published without an
+// envelope carry only Key, and Namespace for a namespace clear. Stamp is the
+// sender's hybrid logical clock value at the time of the write; ID and Origin
+// identify the message and its sender. Clears carry the sender's Epoch.
+type Message struct {
+	ID        string `json:"id,omitempty"`
+	Origin    string `json:"origin,omitempty"`
+	Key       string `json:"key"`
+	Namespace string `json:"ns,omitempty"`
+	Stamp     int64  `json:"ts,omitempty"`
+	Epoch     uint64 `json:"epoch,omitempty"`
+}
+
+// The prefixes start with a control character so that they cannot collide
+// with an ordinary cache key.
+const (
+	batchPrefix    = "\x1finvacache-batch:"
+	envelopePrefix = "\x1finvacache-msg:"
+)
+
+// EncodeMessage wraps m in an envelope. When only Key is set it returns the
+// bare key, which is what drivers and older nodes expect.
+func EncodeMessage(m Message) string {
+	if m == (Message{Key: m.Key}) {
+		return m.Key
+	}
+	data, _ := json.Marshal(m)
+	return envelopePrefix + string(data)
+}
+
+// EncodeBatch packs messages into one message for drivers without a native
+// BatchPublisher. Coordinators unpack it before calling the handler.
+func EncodeBatch(messages []Message) string {
+	data, _ := json.Marshal(messages)
+	return batchPrefix + string(data)
+}
+
+// DecodeMessages unpacks a batch or an envelope. Anything else is a bare key.
+func DecodeMessages(raw string) []Message {
+	if payload, found := strings.CutPrefix(raw, batchPrefix); found {
+		var messages []Message
+		if err := json.Unmarshal([]byte(payload), &messages); err == nil {
+			return messages
+		}
+	}
+	if payload, found := strings.CutPrefix(raw, envelopePrefix); found {
+		var message Message
+		if err := json.Unmarshal([]byte(payload), &message); err == nil {
+			return []Message{message}
+		}
+	}
+	return []Message{{Key: raw}}
+}
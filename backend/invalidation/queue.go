@@ -0,0 +1,271 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+var (
+	ErrQueueFull       = errors.New("invalidation publish queue is full")
+	ErrPublisherClosed = errors.New("invalidation publisher is closed")
+)
+
+// OverflowPolicy decides what Publish does when the queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued invalidation.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowFail returns ErrQueueFull to the writer.
+	OverflowFail OverflowPolicy = "fail"
+)
+
+func (p OverflowPolicy) Valid() bool {
+	switch p {
+	case OverflowBlock, OverflowDropOldest, OverflowFail:
+		return true
+	default:
+		return false
+	}
+}
+
+// QueueConfig configures the publish queue. When BatchWindow is positive the
+// worker waits up to BatchWindow after the first key for up to BatchSize keys
+// and publishes them together.
+type QueueConfig struct {
+	Overflow    OverflowPolicy
+	Backoff     Backoff
+	Size        int
+	MaxRetries  int
+	BatchSize   int
+	BatchWindow time.Duration
+}
+
+// PublishStats counts what happened to invalidations handed to Publish.
+type PublishStats struct {
+	Depth     int   `json:"depth"`
+	Enqueued  int64 `json:"enqueued"`
+	Coalesced int64 `json:"coalesced"`
+	Published int64 `json:"published"`
+	Batches   int64 `json:"batches"`
+	Retries   int64 `json:"retries"`
+	Failed    int64 `json:"failed"`
+	Dropped   int64 `json:"dropped"`
+	Rejected  int64 `json:"rejected"`
+}
+
+// Undelivered counts invalidations that never reached the bus.
+func (s PublishStats) Undelivered() int64 {
+	return s.Failed + s.Dropped + s.Rejected
+}
+
+// queueKey identifies a waiting invalidation; clears of different
+// namespaces are kept apart.
+type queueKey struct {
+	key       string
+	namespace string
+}
+
+// publishQueue is a bounded FIFO of invalidations waiting to be published.
+// A key that is already waiting is not queued twice; the waiting message
+// takes the later stamp and epoch instead.
+type publishQueue struct {
+	queued   map[queueKey]*Message
+	cond     *sync.Cond
+	pending  []*Message
+	cfg      QueueConfig
+	stats    PublishStats
+	mu       sync.Mutex
+	flushing int
+	inflight bool
+	closed   bool
+}
+
+func newPublishQueue(cfg QueueConfig) *publishQueue {
+	if cfg.Size <= 0 {
+		cfg.Size = constant.DefaultPublishQueueSize
+	}
+	if !cfg.Overflow.Valid() {
+		cfg.Overflow = constant.DefaultPublishOverflow
+	}
+	if cfg.BatchWindow <= 0 || cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	q := &publishQueue{cfg: cfg, queued: make(map[queueKey]*Message)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *publishQueue) enqueue(message Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return ErrPublisherClosed
+		}
+		if waiting, exists := q.queued[message.queueKey()]; exists {
+			waiting.Stamp = max(waiting.Stamp, message.Stamp)
+			waiting.Epoch = max(waiting.Epoch, message.Epoch)
+			q.stats.Coalesced++
+			return nil
+		}
+		if len(q.pending) < q.cfg.Size {
+			break
+		}
+
+		switch q.cfg.Overflow {
+		case OverflowFail:
+			q.stats.Rejected++
+			return ErrQueueFull
+		case OverflowDropOldest:
+			delete(q.queued, q.pending[0].queueKey())
+			q.pending = q.pending[1:]
+			q.stats.Dropped++
+		default:
+			q.cond.Wait()
+		}
+	}
+
+	q.pending = append(q.pending, &message)
+	q.queued[message.queueKey()] = &message
+	q.stats.Enqueued++
+	q.cond.Broadcast()
+	return nil
+}
+
+// next blocks until a key is available and marks the keys it returns in
+// flight. With batching it keeps collecting until the batch is full, the
+// window has passed or a flush is waiting. It returns false once the queue is
+// closed.
+func (q *publishQueue) next() ([]Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+
+	if q.cfg.BatchSize > 1 && len(q.pending) < q.cfg.BatchSize {
+		expired := false
+		timer := time.AfterFunc(q.cfg.BatchWindow, func() {
+			q.mu.Lock()
+			expired = true
+			q.cond.Broadcast()
+			q.mu.Unlock()
+		})
+		for !expired && !q.closed && q.flushing == 0 && len(q.pending) < q.cfg.BatchSize {
+			q.cond.Wait()
+		}
+		timer.Stop()
+		if q.closed {
+			return nil, false
+		}
+	}
+
+	n := min(len(q.pending), q.cfg.BatchSize)
+	messages := make([]Message, n)
+	for idx, message := range q.pending[:n] {
+		messages[idx] = *message
+		delete(q.queued, message.queueKey())
+	}
+	q.pending = q.pending[n:]
+	q.inflight = true
+	q.cond.Broadcast()
+	return messages, true
+}
+
+func (q *publishQueue) done(keys int, published bool) {
+	q.mu.Lock()
+	q.count(keys, published)
+	q.inflight = false
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+func (q *publishQueue) record(keys int, published bool) {
+	q.mu.Lock()
+	q.count(keys, published)
+	q.mu.Unlock()
+}
+
+func (q *publishQueue) count(keys int, published bool) {
+	if !published {
+		q.stats.Failed += int64(keys)
+		return
+	}
+	q.stats.Published += int64(keys)
+	if keys > 1 {
+		q.stats.Batches++
+	}
+}
+
+func (q *publishQueue) retried() {
+	q.mu.Lock()
+	q.stats.Retries++
+	q.mu.Unlock()
+}
+
+func (q *publishQueue) flush(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.flushing++
+	q.cond.Broadcast()
+	defer func() { q.flushing-- }()
+	for len(q.pending) > 0 || q.inflight {
+		if q.closed {
+			return ErrPublisherClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.cond.Wait()
+	}
+	return nil
+}
+
+func (q *publishQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+func (q *publishQueue) snapshot() PublishStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Depth = len(q.pending)
+	return stats
+}
+
+func (m *Message) queueKey() queueKey {
+	return queueKey{key: m.Key, namespace: m.Namespace}
+}
+
+func retryDelay(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,249 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+// gatedPubSub records published keys; each Publish waits for gate and fails
+// while failures is positive.
+type gatedPubSub struct {
+	gate      chan struct{}
+	published []string
+	failures  int
+	mu        sync.Mutex
+}
+
+func (p *gatedPubSub) Publish(ctx context.Context, key string) error {
+	if p.gate != nil {
+		select {
+		case <-p.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("bus unavailable")
+	}
+	p.published = append(p.published, key)
+	return nil
+}
+
+func (p *gatedPubSub) Subscribe(ctx context.Context, _ InvalidationHandler) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *gatedPubSub) Close() error { return nil }
+
+func (p *gatedPubSub) keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func TestPublishQueueCoalescesPendingKeys(t *testing.T) {
+	q := newPublishQueue(QueueConfig{Size: 4, Overflow: OverflowFail})
+
+	for _, key := range []string{"a", "b", "a", "a", ""} {
+		if err := q.enqueue(Message{Key: key}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	stats := q.snapshot()
+	if stats.Depth != 3 || stats.Enqueued != 3 || stats.Coalesced != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	_ = q.enqueue(Message{Namespace: "user", Epoch: 2})
+	_ = q.enqueue(Message{Namespace: "user", Epoch: 3})
+	if stats := q.snapshot(); stats.Depth != 4 || stats.Coalesced != 3 {
+		t.Errorf("expected namespace clears to be kept apart from the full clear, got %+v", stats)
+	}
+	if waiting := q.queued[queueKey{namespace: "user"}]; waiting.Epoch != 3 {
+		t.Errorf("expected the coalesced clear to keep the later epoch, got %d", waiting.Epoch)
+	}
+}
+
+func TestPublishQueueOverflowPolicies(t *testing.T) {
+	dropping := newPublishQueue(QueueConfig{Size: 2, Overflow: OverflowDropOldest})
+	for _, key := range []string{"a", "b", "c"} {
+		if err := dropping.enqueue(Message{Key: key}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if keys, _ := dropping.next(); keys[0].Key != "b" {
+		t.Errorf("expected the oldest key to be dropped, got %q first", keys[0])
+	}
+	if stats := dropping.snapshot(); stats.Dropped != 1 {
+		t.Errorf("expected 1 dropped, got %+v", stats)
+	}
+
+	failing := newPublishQueue(QueueConfig{Size: 1, Overflow: OverflowFail})
+	_ = failing.enqueue(Message{Key: "a"})
+	if err := failing.enqueue(Message{Key: "b"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	blocking := newPublishQueue(QueueConfig{Size: 1, Overflow: OverflowBlock})
+	_ = blocking.enqueue(Message{Key: "a"})
+	enqueued := make(chan error, 1)
+	go func() { enqueued <- blocking.enqueue(Message{Key: "b"}) }()
+
+	select {
+	case err := <-enqueued:
+		t.Fatalf("expected enqueue to block on a full queue, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	blocking.next()
+	if err := <-enqueued; err != nil {
+		t.Errorf("unexpected error after room was made: %v", err)
+	}
+
+	blocking.close()
+	if err := blocking.enqueue(Message{Key: "c"}); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("expected ErrPublisherClosed, got %v", err)
+	}
+	if err := blocking.flush(context.Background()); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("expected flush to report the keys left behind, got %v", err)
+	}
+}
+
+func TestCoordinatorPublishRetriesAndFlushes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &gatedPubSub{failures: 2}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithPublishQueue(QueueConfig{
+		MaxRetries: 3,
+		Backoff:    Backoff{Initial: time.Millisecond, Max: time.Millisecond, Multiplier: 1},
+	}))
+	go c.RunPublisher()
+
+	_ = c.Publish(Message{Key: "user:1"})
+	_ = c.Publish(Message{Key: "user:2"})
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if keys := pubsub.keys(); len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Errorf("expected both keys published in order, got %v", keys)
+	}
+	stats := c.Health().Publish
+	if stats.Published != 2 || stats.Retries != 2 || stats.Failed != 0 || stats.Depth != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCoordinatorFlushHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &gatedPubSub{gate: make(chan struct{})}
+	c := NewCoordinator(ctx, pubsub, logger.Discard())
+	go c.RunPublisher()
+	_ = c.Publish(Message{Key: "user:1"})
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if err := c.Flush(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(pubsub.gate)
+	if err := c.Flush(context.Background()); err != nil {
+		t.Errorf("unexpected flush error once the bus recovered: %v", err)
+	}
+}
+
+func TestCoordinatorFlushOnCloseIsBounded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &gatedPubSub{gate: make(chan struct{})}
+	c := NewCoordinator(ctx, pubsub, logger.Discard())
+	c.closeFlush = 10 * time.Millisecond
+	go c.RunPublisher()
+	_ = c.Publish(Message{Key: "user:1"})
+
+	started := time.Now()
+	if err := c.FlushOnClose(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the flush to give up, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the flush to be bounded, took %v", elapsed)
+	}
+	close(pubsub.gate)
+}
+
+// batchPubSub records native batches.
+type batchPubSub struct {
+	gatedPubSub
+	batches [][]string
+}
+
+func (p *batchPubSub) PublishBatch(_ context.Context, keys []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, keys)
+	return nil
+}
+
+func TestCoordinatorBatchesWithinWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &batchPubSub{}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithPublishQueue(QueueConfig{BatchSize: 3, BatchWindow: time.Hour}))
+	go c.RunPublisher()
+
+	for _, key := range []string{"a", "b", "a", "c", "d"} {
+		_ = c.Publish(Message{Key: key})
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	pubsub.mu.Lock()
+	batches := pubsub.batches
+	single := pubsub.published
+	pubsub.mu.Unlock()
+	if len(batches) != 1 || len(batches[0]) != 3 || batches[0][0] != "a" || batches[0][2] != "c" {
+		t.Errorf("expected one deduplicated batch of a, b, c, got %v", batches)
+	}
+	if len(single) != 1 || single[0] != "d" {
+		t.Errorf("expected the flush to cut the window short for d, got %v", single)
+	}
+	if stats := c.Health().Publish; stats.Published != 4 || stats.Batches != 1 || stats.Coalesced != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCoordinatorEncodesBatchWithoutBatchPublisher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := &gatedPubSub{}
+	c := NewCoordinator(ctx, pubsub, logger.Discard(), WithPublishQueue(QueueConfig{BatchSize: 2, BatchWindow: time.Hour}))
+	go c.RunPublisher()
+
+	_ = c.Publish(Message{Key: "user:1"})
+	_ = c.Publish(Message{})
+	_ = c.Flush(context.Background())
+
+	keys := pubsub.keys()
+	if len(keys) != 1 {
+		t.Fatalf("expected one encoded message, got %v", keys)
+	}
+	decoded := DecodeMessages(keys[0])
+	if len(decoded) != 2 || decoded[0].Key != "user:1" || !IsClearEvent(decoded[1].Key) {
+		t.Errorf("unexpected batch %q decoded as %v", keys[0], decoded)
+	}
+	if plain := DecodeMessages("user:1"); len(plain) != 1 || plain[0] != (Message{Key: "user:1"}) {
+		t.Errorf("expected a plain key to decode as itself, got %v", plain)
+	}
+}
@@ -0,0 +1,125 @@
+package invalidation
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type ConnectionState int32
+
+const (
+	StateDisabled ConnectionState = iota
+	StateConnecting
+	StateConnected
+	StateReconnecting
+	StateClosed
+	// StateUnconfirmed means Subscribe is running on a driver that cannot
+	// confirm the subscription, because it does not implement ReadySubscriber.
+	StateUnconfirmed
+)
+
+func (s ConnectionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisabled:
+		return "disabled"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	case StateUnconfirmed:
+		return "unconfirmed"
+	default:
+		return "unknown"
+	}
+}
+
+// Backoff is the delay between resubscription attempts. Attempt n waits
+// Initial*Multiplier^n capped at Max, reduced by a random fraction of up to
+// Jitter so that nodes dropped together do not reconnect together.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt))
+	if delay > float64(b.Max) || math.IsInf(delay, 1) {
+		delay = float64(b.Max)
+	}
+	delay -= delay * b.Jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+type CoordinatorOption func(*Coordinator)
+
+func WithBackoff(backoff Backoff) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.backoff = backoff
+	}
+}
+
+// WithGapHandler sets a function called before resubscribing after the
+// subscription dropped, when invalidations may have been missed.
+func WithGapHandler(onGap func()) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.onGap = onGap
+	}
+}
+
+// WithPublishQueue sets the size, overflow policy and retry behaviour of the
+// publish queue.
+func WithPublishQueue(cfg QueueConfig) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.queueConfig = cfg
+	}
+}
+
+// WithDispatch runs the invalidation handler on a pool of workers instead of
+// the subscription goroutine.
+func WithDispatch(cfg DispatchConfig) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.dispatch = cfg
+	}
+}
+
+// WithEnvelope wraps published invalidations in an envelope carrying a
+// message ID, this node's ID and a hybrid logical clock stamp. Received IDs
+// are remembered for dedupeWindow so that redeliveries are dropped.
+func WithEnvelope(dedupeWindow time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.envelope = true
+		if dedupeWindow > 0 {
+			c.dedupeWindow = dedupeWindow
+		}
+	}
+}
+
+// WithDeleteIfOlder handles stamped invalidations instead of the
+// invalidation handler. deleteIfOlder must compare stamp with the key's last
+// local write and delete the key only if that write is older, under the same
+// lock, and report whether it deleted.
+func WithDeleteIfOlder(deleteIfOlder func(key string, stamp int64) (bool, error)) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.deleteIfOlder = deleteIfOlder
+	}
+}
+
+// WithClearHandler receives clear events instead of the invalidation handler,
+// with the namespace being cleared (empty for the whole cache) and the
+// sender's clear epoch (zero when it was not sent).
+func WithClearHandler(onClear func(namespace string, epoch uint64) error) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.onClear = onClear
+	}
+}
@@ -0,0 +1,446 @@
+package offheap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/codec"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+	"github.com/halilbulentorhon/invacache-go/pkg/singleflight"
+)
+
+type offHeapBackend[V any] struct {
+	ctx          context.Context
+	invalidator  *invalidation.Coordinator
+	delivery     option.DeliveryDefaults
+	logger       logger.Logger
+	codec        codec.Codec[V]
+	cancel       context.CancelFunc
+	singleFlight singleflight.Group[V]
+	closeErr     error
+	shards       []*ringShard
+	wg           sync.WaitGroup
+	defaultTTL   time.Duration
+	closeOnce    sync.Once
+	closed       atomic.Bool
+	// epoch counts clears, starting at 1 like the in-memory backend's.
+	epoch atomic.Uint64
+}
+
+func (o *offHeapBackend[V]) getShard(hash uint64) *ringShard {
+	return o.shards[hash%uint64(len(o.shards))]
+}
+
+func (o *offHeapBackend[V]) Get(key string) (V, error) {
+	value, found, err := o.get(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	if !found {
+		var zero V
+		return zero, constant.ErrNotFound
+	}
+	return value, nil
+}
+
+func (o *offHeapBackend[V]) get(key string) (V, bool, error) {
+	hash := hashKey(key)
+	shard := o.getShard(hash)
+
+	shard.mu.RLock()
+	stored, found := shard.get(key, hash, time.Now())
+	var data []byte
+	if found {
+		data = make([]byte, len(stored))
+		copy(data, stored)
+	}
+	shard.mu.RUnlock()
+
+	var zero V
+	if !found {
+		return zero, false, nil
+	}
+
+	value, err := o.codec.Unmarshal(data)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to decode value for key %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (o *offHeapBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V]) (V, error) {
+	if value, found, err := o.get(key); err == nil && found {
+		return value, nil
+	}
+	if o.closed.Load() {
+		var zero V
+		return zero, constant.ErrClosed
+	}
+
+	// A clear while the loader runs may have removed what it read.
+	epoch := o.epoch.Load()
+	value, ttl, err := o.singleFlight.Do(key, func() (V, time.Duration, error) {
+		return loader(key)
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	if existing, found, err := o.get(key); err == nil && found {
+		return existing, nil
+	}
+
+	setErr := o.set(key, value, option.WithTTL(ttl), option.WithEpoch(epoch))
+	if setErr != nil && !errors.Is(setErr, constant.ErrStaleEpoch) {
+		var zero V
+		return zero, setErr
+	}
+	return value, nil
+}
+
+func (o *offHeapBackend[V]) Set(key string, value V, options ...option.OptFnc) error {
+	if err := o.set(key, value, options...); err != nil {
+		return err
+	}
+
+	cfg := option.ApplyOptions(options)
+	return o.publishInvalidation(invalidation.Message{Key: key}, cfg.Delivery.Or(o.delivery.Set))
+}
+
+func (o *offHeapBackend[V]) set(key string, value V, options ...option.OptFnc) error {
+	if o.closed.Load() {
+		return constant.ErrClosed
+	}
+	cfg := option.ApplyOptions(options)
+
+	var expiresAt int64
+	if !cfg.NoExpiration {
+		ttl := cfg.TTL
+		if ttl == 0 {
+			ttl = o.defaultTTL
+		}
+		if ttl > 0 {
+			expiresAt = time.Now().Add(ttl).UnixNano()
+		}
+	}
+
+	data, err := o.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for key %s: %w", key, err)
+	}
+
+	hash := hashKey(key)
+	shard := o.getShard(hash)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if cfg.Epoch != 0 && cfg.Epoch < o.epoch.Load() {
+		return fmt.Errorf("%w: %s", constant.ErrStaleEpoch, key)
+	}
+	return shard.set(key, hash, data, expiresAt)
+}
+
+func (o *offHeapBackend[V]) Delete(key string, options ...option.DelOptFnc) error {
+	if o.closed.Load() {
+		return constant.ErrClosed
+	}
+	hash := hashKey(key)
+	shard := o.getShard(hash)
+	shard.mu.Lock()
+	shard.delete(key, hash)
+	shard.mu.Unlock()
+
+	cfg := option.ApplyDeleteOptions(options)
+	return o.publishInvalidation(invalidation.Message{Key: key}, cfg.Delivery.Or(o.delivery.Delete))
+}
+
+func (o *offHeapBackend[V]) Clear(options ...option.ClrOptFnc) error {
+	if o.closed.Load() {
+		return constant.ErrClosed
+	}
+	cfg := option.ApplyClearOptions(options)
+	epoch := o.epoch.Add(1)
+	o.clear(cfg)
+	return o.publishInvalidation(invalidation.Message{Namespace: cfg.Namespace, Epoch: epoch}, cfg.Delivery.Or(o.delivery.Clear))
+}
+
+// clear removes the entries cfg covers. The epoch must already have been
+// advanced, so that writes tagged with an older epoch are refused.
+func (o *offHeapBackend[V]) clear(cfg option.ClearConfig) {
+	for _, shard := range o.shards {
+		shard.mu.Lock()
+		if cfg.Namespace == "" {
+			shard.clear()
+		} else {
+			shard.deleteMatching(cfg.Matches)
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// Epoch returns the current clear epoch; see backend.EpochReporter.
+func (o *offHeapBackend[V]) Epoch() uint64 {
+	return o.epoch.Load()
+}
+
+func (o *offHeapBackend[V]) Health() backend.Health {
+	return backend.Health{
+		Backend:      constant.OffHeapBackend,
+		Closed:       o.closed.Load(),
+		Invalidation: o.invalidator.Health(),
+	}
+}
+
+func (o *offHeapBackend[V]) WaitReady(ctx context.Context) error {
+	return o.invalidator.WaitReady(ctx)
+}
+
+func (o *offHeapBackend[V]) Flush(ctx context.Context) error {
+	return o.invalidator.Flush(ctx)
+}
+
+func (o *offHeapBackend[V]) InvalidationState() invalidation.ConnectionState {
+	return o.invalidator.State()
+}
+
+func (o *offHeapBackend[V]) publishInvalidation(message invalidation.Message, delivery option.Delivery) error {
+	if o.invalidator == nil {
+		return nil
+	}
+	message.Stamp = o.invalidator.Stamp()
+	switch delivery {
+	case option.DeliveryAsync:
+		return o.invalidator.Publish(message)
+	case option.DeliverySync:
+		return o.invalidator.PublishSync(message)
+	default:
+		return nil
+	}
+}
+
+func (o *offHeapBackend[V]) handleInvalidationMessage(key string) error {
+	if invalidation.IsClearEvent(key) {
+		return o.Clear(option.WithClearDelivery(option.DeliveryLocal))
+	}
+	return o.Delete(key, option.WithDeleteDelivery(option.DeliveryLocal))
+}
+
+// handleClearMessage applies a peer's clear, moving the local epoch past both
+// its own value and the peer's.
+func (o *offHeapBackend[V]) handleClearMessage(namespace string, epoch uint64) error {
+	for {
+		current := o.epoch.Load()
+		if o.epoch.CompareAndSwap(current, max(current+1, epoch)) {
+			break
+		}
+	}
+	o.clear(option.ClearConfig{Namespace: namespace})
+	return nil
+}
+
+func (o *offHeapBackend[V]) Len() int {
+	return len(o.Keys())
+}
+
+func (o *offHeapBackend[V]) Keys() []string {
+	var keys []string
+	now := time.Now()
+	for _, shard := range o.shards {
+		shard.mu.RLock()
+		keys = append(keys, shard.liveKeys(now)...)
+		shard.mu.RUnlock()
+	}
+	return keys
+}
+
+func (o *offHeapBackend[V]) Range(fn func(key string, value V) bool) {
+	now := time.Now()
+	for _, shard := range o.shards {
+		shard.mu.RLock()
+		keys := shard.liveKeys(now)
+		shard.mu.RUnlock()
+
+		for _, key := range keys {
+			value, found, err := o.get(key)
+			if err != nil {
+				o.logger.Warn("failed to read key during range", "key", key, "error", err)
+				continue
+			}
+			if !found {
+				continue
+			}
+			if !fn(key, value) {
+				return
+			}
+		}
+	}
+}
+
+func (o *offHeapBackend[V]) runSweeper(interval time.Duration) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, shard := range o.shards {
+				shard.mu.Lock()
+				shard.sweepExpired(time.Now())
+				shard.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (o *offHeapBackend[V]) Close() error {
+	return o.CloseContext(context.Background())
+}
+
+// CloseContext stops accepting writes, flushes queued invalidations, cancels
+// the sweeper and the invalidation subscription and waits for them to exit
+// before closing the invalidation driver. Without a deadline on ctx, the flush
+// gives up after constant.DefaultCloseFlushTimeout. If ctx ends first the
+// driver is still closed and ctx's error is returned. Later calls return the
+// result of the first one.
+func (o *offHeapBackend[V]) CloseContext(ctx context.Context) error {
+	o.closeOnce.Do(func() {
+		o.closeErr = o.shutdown(ctx)
+	})
+	return o.closeErr
+}
+
+func (o *offHeapBackend[V]) shutdown(ctx context.Context) error {
+	o.logger.Info("closing off-heap cache")
+	o.closed.Store(true)
+
+	if err := o.invalidator.FlushOnClose(ctx); err != nil {
+		o.logger.Warn("pending invalidations were not published", "error", err)
+	}
+
+	o.cancel()
+	waitErr := o.waitForBackground(ctx)
+	if waitErr != nil {
+		o.logger.Warn("background goroutines did not stop in time", "error", waitErr)
+	}
+
+	if o.invalidator != nil {
+		if err := o.invalidator.Close(); err != nil {
+			o.logger.Error("failed to close invalidator", "error", err)
+			return err
+		}
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+	o.logger.Info("off-heap cache closed successfully")
+	return nil
+}
+
+func (o *offHeapBackend[V]) waitForBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func NewOffHeapBackend[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	log := cfg.NewLogger("off-heap-cache")
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.OffHeap == nil {
+		cfg.Backend.OffHeap = &config.OffHeapConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	valueCodec, err := codec.New[V](cfg.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec: %w", err)
+	}
+
+	offHeapCfg := cfg.Backend.OffHeap
+	log.Info("initializing off-heap cache",
+		"shard_count", offHeapCfg.ShardCount,
+		"max_bytes", offHeapCfg.MaxBytes,
+		"sweeper_interval", offHeapCfg.SweeperInterval)
+
+	shards := make([]*ringShard, offHeapCfg.ShardCount)
+	for i := range shards {
+		shards[i] = newRingShard(int(offHeapCfg.MaxBytes / int64(offHeapCfg.ShardCount)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	be := &offHeapBackend[V]{
+		ctx:          ctx,
+		logger:       log,
+		codec:        valueCodec,
+		cancel:       cancel,
+		singleFlight: singleflight.Group[V]{},
+		shards:       shards,
+		defaultTTL:   offHeapCfg.DefaultTTL,
+	}
+	be.epoch.Store(1)
+
+	be.wg.Add(1)
+	go be.runSweeper(offHeapCfg.SweeperInterval)
+
+	if cfg.Invalidation != nil {
+		log.Info("initializing invalidation", "type", cfg.Invalidation.Type)
+		inv := cfg.Invalidation.PubSub
+		var err error
+		if inv == nil {
+			inv, err = invalidation.NewInvalidatorWithLogger(cfg.Invalidation.Type, cfg.Invalidation.DriverConfig, log.With("driver", cfg.Invalidation.Type))
+		}
+		if err != nil {
+			cancel()
+			be.wg.Wait()
+			log.Error("failed to create invalidator", "type", cfg.Invalidation.Type, "error", err)
+			return nil, fmt.Errorf("failed to create invalidator: %w", err)
+		}
+		onGap := func() {
+			if err := be.Clear(option.WithClearDelivery(option.DeliveryLocal)); err != nil {
+				log.Warn("failed to clear cache after invalidation gap", "error", err)
+			}
+		}
+		opts := append(cfg.Invalidation.CoordinatorOptions(onGap), invalidation.WithClearHandler(be.handleClearMessage))
+		be.invalidator = invalidation.NewCoordinator(ctx, inv, log, opts...)
+		be.delivery = cfg.Invalidation.DeliveryDefaults()
+		log.Info("invalidation initialized successfully", "type", cfg.Invalidation.Type)
+		be.wg.Add(2)
+		go func() {
+			defer be.wg.Done()
+			be.invalidator.Run(be.handleInvalidationMessage)
+		}()
+		go func() {
+			defer be.wg.Done()
+			be.invalidator.RunPublisher()
+		}()
+	}
+
+	return be, nil
+}
@@ -0,0 +1,242 @@
+package offheap
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+func createTestCache[V any](t testing.TB, maxBytes int64) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			OffHeap: &config.OffHeapConfig{
+				ShardCount:      4,
+				MaxBytes:        maxBytes,
+				SweeperInterval: time.Minute,
+			},
+		},
+	}
+
+	cache, err := NewOffHeapBackend[V](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	return cache
+}
+
+func TestOffHeapSetAndGet(t *testing.T) {
+	cache := createTestCache[string](t, 1<<20)
+	defer cache.Close()
+
+	if err := cache.Set("key1", "value1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := cache.Get("key1")
+	if err != nil || value != "value1" {
+		t.Errorf("expected value1, got %q, %v", value, err)
+	}
+
+	_, err = cache.Get("missing")
+	if err != constant.ErrNotFound {
+		t.Errorf("expected key not found error, got %v", err)
+	}
+}
+
+func TestOffHeapSetWithTTL(t *testing.T) {
+	cache := createTestCache[string](t, 1<<20)
+	defer cache.Close()
+
+	_ = cache.Set("key1", "value1", option.WithTTL(50*time.Millisecond))
+	_ = cache.Set("key2", "value2", option.WithNoExpiration())
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := cache.Get("key1"); err == nil {
+		t.Error("expected key1 to expire")
+	}
+	if _, err := cache.Get("key2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestOffHeapDeleteClearAndRange(t *testing.T) {
+	cache := createTestCache[int](t, 1<<20)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		_ = cache.Set(fmt.Sprintf("key%d", i), i)
+	}
+	_ = cache.Delete("key0")
+
+	if cache.Len() != 9 {
+		t.Errorf("expected 9 entries, got %d", cache.Len())
+	}
+
+	sum := 0
+	cache.Range(func(_ string, value int) bool {
+		sum += value
+		return true
+	})
+	if sum != 45 {
+		t.Errorf("expected sum 45, got %d", sum)
+	}
+
+	_ = cache.Set("ns:key1", 1)
+	_ = cache.Clear(option.WithNamespace("ns"))
+	if cache.Len() != 9 {
+		t.Errorf("expected only the namespace to be cleared, got %v", cache.Keys())
+	}
+
+	_ = cache.Clear()
+	if cache.Len() != 0 {
+		t.Error("expected empty cache after clear")
+	}
+}
+
+func TestOffHeapEvictsWhenFull(t *testing.T) {
+	cache := createTestCache[[]byte](t, 4*1024)
+	defer cache.Close()
+
+	payload := make([]byte, 100)
+	for i := 0; i < 200; i++ {
+		if err := cache.Set(fmt.Sprintf("key%d", i), payload); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if cache.Len() >= 200 {
+		t.Errorf("expected older entries to be evicted, got %d", cache.Len())
+	}
+	if _, err := cache.Get("key199"); err != nil {
+		t.Errorf("expected newest entry to remain: %v", err)
+	}
+}
+
+func TestOffHeapGetOrLoad(t *testing.T) {
+	cache := createTestCache[string](t, 1<<20)
+	defer cache.Close()
+
+	calls := 0
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrLoad("key1", func(string) (string, time.Duration, error) {
+			calls++
+			return "loaded", time.Minute, nil
+		})
+		if err != nil || value != "loaded" {
+			t.Fatalf("unexpected result: %q, %v", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected loader to run once, ran %d times", calls)
+	}
+
+	_, err := cache.GetOrLoad("failing", func(string) (string, time.Duration, error) {
+		return "", 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected loader error")
+	}
+}
+
+func TestOffHeapConcurrentAccess(t *testing.T) {
+	cache := createTestCache[int](t, 1<<20)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("key%d", i%50)
+				_ = cache.Set(key, g*i)
+				_, _ = cache.Get(key)
+				if i%10 == 0 {
+					_ = cache.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestOffHeapClearEpoch(t *testing.T) {
+	cache := createTestCache[string](t, 1<<20)
+	defer cache.Close()
+	reporter := cache.(backend.EpochReporter)
+
+	epoch := reporter.Epoch()
+	if err := cache.Clear(option.WithNamespace("user")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Set("user:1", "stale", option.WithEpoch(epoch)); !errors.Is(err, constant.ErrStaleEpoch) {
+		t.Errorf("expected ErrStaleEpoch, got %v", err)
+	}
+	if err := cache.Set("user:1", "fresh", option.WithEpoch(reporter.Epoch())); err != nil {
+		t.Errorf("unexpected error for a current epoch: %v", err)
+	}
+
+	o := cache.(*offHeapBackend[string])
+	_ = o.handleClearMessage("", reporter.Epoch()+5)
+	if cache.Len() != 0 || reporter.Epoch() != epoch+6 {
+		t.Errorf("expected a received clear to empty the cache and adopt the peer's epoch, got %d keys at epoch %d", cache.Len(), reporter.Epoch())
+	}
+
+	value, err := cache.GetOrLoad("user:2", func(string) (string, time.Duration, error) {
+		_ = cache.Clear()
+		return "loaded", time.Minute, nil
+	})
+	if err != nil || value != "loaded" {
+		t.Fatalf("unexpected result: %q, %v", value, err)
+	}
+	if _, err := cache.Get("user:2"); err == nil {
+		t.Error("expected a load that raced a clear not to be stored")
+	}
+}
+
+func TestOffHeapCloseStopsWrites(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	cache := createTestCache[string](t, 1<<20)
+	if err := cache.Set("key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("expected a second Close to return the first result, got %v", err)
+	}
+	if !cache.(backend.HealthReporter).Health().Closed {
+		t.Error("expected Health to report the cache closed")
+	}
+
+	if err := cache.Set("other", "value"); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Set, got %v", err)
+	}
+	if err := cache.Delete("key"); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Delete, got %v", err)
+	}
+	if err := cache.Clear(); !errors.Is(err, constant.ErrClosed) {
+		t.Errorf("expected ErrClosed from Clear, got %v", err)
+	}
+	if value, err := cache.Get("key"); err != nil || value != "value" {
+		t.Errorf("expected reads to keep working after Close, got %q, %v", value, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Errorf("expected the sweeper to have exited, got %d goroutines, started with %d", n, baseline)
+	}
+}
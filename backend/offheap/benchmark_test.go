@@ -0,0 +1,119 @@
+package offheap
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/inmemory"
+	"github.com/halilbulentorhon/invacache-go/config"
+)
+
+const benchmarkEntries = 1_000_000
+
+func newBenchmarkInMemory(b *testing.B) backend.Cache[[]byte] {
+	cache, err := inmemory.NewInMemoryBackend[[]byte](config.InvaCacheConfig{
+		Codec: "raw",
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{
+				ShardCount:      64,
+				Capacity:        benchmarkEntries,
+				SweeperInterval: time.Hour,
+			},
+		},
+	})
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	return cache
+}
+
+func newBenchmarkOffHeap(b *testing.B) backend.Cache[[]byte] {
+	cache, err := NewOffHeapBackend[[]byte](config.InvaCacheConfig{
+		Codec: "raw",
+		Backend: &config.BackendConfig{
+			OffHeap: &config.OffHeapConfig{
+				ShardCount:      64,
+				MaxBytes:        256 << 20,
+				SweeperInterval: time.Hour,
+			},
+		},
+	})
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	return cache
+}
+
+func benchmarkSet(b *testing.B, cache backend.Cache[[]byte]) {
+	defer cache.Close()
+	value := make([]byte, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_ = cache.Set(strconv.Itoa(i%benchmarkEntries), value)
+			i++
+		}
+	})
+}
+
+func benchmarkGet(b *testing.B, cache backend.Cache[[]byte]) {
+	defer cache.Close()
+	value := make([]byte, 64)
+	for i := 0; i < 10_000; i++ {
+		_ = cache.Set(strconv.Itoa(i), value)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = cache.Get(strconv.Itoa(i % 10_000))
+			i++
+		}
+	})
+}
+
+// benchmarkGCPause fills the cache and then measures how long forced
+// collections take while the entries are live, which is where pointer-heavy
+// caches pay for their layout.
+func benchmarkGCPause(b *testing.B, cache backend.Cache[[]byte]) {
+	defer cache.Close()
+	value := make([]byte, 64)
+	for i := 0; i < benchmarkEntries; i++ {
+		_ = cache.Set(fmt.Sprintf("key-%d", i), value)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+	}
+	b.StopTimer()
+
+	runtime.ReadMemStats(&after)
+	if gcs := after.NumGC - before.NumGC; gcs > 0 {
+		b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(gcs), "pause-ns/gc")
+	}
+	b.ReportMetric(float64(after.HeapObjects), "heap-objects")
+	runtime.KeepAlive(cache)
+}
+
+func BenchmarkSetInMemory(b *testing.B) { benchmarkSet(b, newBenchmarkInMemory(b)) }
+func BenchmarkSetOffHeap(b *testing.B)  { benchmarkSet(b, newBenchmarkOffHeap(b)) }
+
+func BenchmarkGetInMemory(b *testing.B) { benchmarkGet(b, newBenchmarkInMemory(b)) }
+func BenchmarkGetOffHeap(b *testing.B)  { benchmarkGet(b, newBenchmarkOffHeap(b)) }
+
+func BenchmarkGCPauseInMemory(b *testing.B) { benchmarkGCPause(b, newBenchmarkInMemory(b)) }
+func BenchmarkGCPauseOffHeap(b *testing.B)  { benchmarkGCPause(b, newBenchmarkOffHeap(b)) }
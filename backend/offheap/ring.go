@@ -0,0 +1,192 @@
+package offheap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	entryHeaderSize = 4 + 8 + 8 + 2
+	maxKeyLength    = 1<<16 - 1
+
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// ringShard keeps serialized entries in one preallocated byte buffer used as a
+// FIFO ring. The index only holds integers, so the GC never scans entries.
+// Overwritten and deleted entries stay in the buffer as dead space until the
+// head passes them.
+type ringShard struct {
+	index   map[uint64]uint32
+	buf     []byte
+	head    uint32
+	tail    uint32
+	wrapAt  uint32
+	records int
+	mu      sync.RWMutex
+	wrapped bool
+}
+
+func newRingShard(size int) *ringShard {
+	return &ringShard{
+		index: make(map[uint64]uint32),
+		buf:   make([]byte, size),
+	}
+}
+
+func hashKey(key string) uint64 {
+	var h uint64 = fnvOffset64
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+func (s *ringShard) get(key string, hash uint64, now time.Time) ([]byte, bool) {
+	offset, exists := s.index[hash]
+	if !exists {
+		return nil, false
+	}
+
+	header := s.buf[offset : offset+entryHeaderSize]
+	keyLen := uint32(binary.LittleEndian.Uint16(header[20:]))
+	keyStart := offset + entryHeaderSize
+	if string(s.buf[keyStart:keyStart+keyLen]) != key {
+		return nil, false
+	}
+	if isExpired(int64(binary.LittleEndian.Uint64(header[4:])), now) {
+		return nil, false
+	}
+
+	end := offset + binary.LittleEndian.Uint32(header)
+	return s.buf[keyStart+keyLen : end], true
+}
+
+func (s *ringShard) set(key string, hash uint64, value []byte, expiresAt int64) error {
+	if len(key) > maxKeyLength {
+		return fmt.Errorf("key length %d exceeds maximum %d", len(key), maxKeyLength)
+	}
+	size := entryHeaderSize + len(key) + len(value)
+	if size > len(s.buf) {
+		return fmt.Errorf("entry for key %s is %d bytes, larger than shard capacity %d", key, size, len(s.buf))
+	}
+
+	offset := s.allocate(uint32(size))
+	entry := s.buf[offset : offset+uint32(size)]
+	binary.LittleEndian.PutUint32(entry, uint32(size))
+	binary.LittleEndian.PutUint64(entry[4:], uint64(expiresAt))
+	binary.LittleEndian.PutUint64(entry[12:], hash)
+	binary.LittleEndian.PutUint16(entry[20:], uint16(len(key)))
+	copy(entry[entryHeaderSize:], key)
+	copy(entry[entryHeaderSize+len(key):], value)
+
+	s.index[hash] = offset
+	return nil
+}
+
+func (s *ringShard) delete(key string, hash uint64) {
+	offset, exists := s.index[hash]
+	if !exists {
+		return
+	}
+	if s.keyAt(offset) == key {
+		delete(s.index, hash)
+	}
+}
+
+func (s *ringShard) allocate(size uint32) uint32 {
+	for {
+		if s.records == 0 {
+			s.head, s.tail, s.wrapped = 0, 0, false
+		}
+
+		if !s.wrapped {
+			if s.tail+size <= uint32(len(s.buf)) {
+				break
+			}
+			s.wrapAt = s.tail
+			s.tail = 0
+			s.wrapped = true
+			continue
+		}
+
+		if s.tail+size <= s.head {
+			break
+		}
+		s.evictHead()
+	}
+
+	offset := s.tail
+	s.tail += size
+	s.records++
+	return offset
+}
+
+func (s *ringShard) evictHead() {
+	header := s.buf[s.head : s.head+entryHeaderSize]
+	hash := binary.LittleEndian.Uint64(header[12:])
+	if offset, exists := s.index[hash]; exists && offset == s.head {
+		delete(s.index, hash)
+	}
+
+	s.head += binary.LittleEndian.Uint32(header)
+	s.records--
+	if s.wrapped && s.head >= s.wrapAt {
+		s.head = 0
+		s.wrapped = false
+	}
+}
+
+func (s *ringShard) keyAt(offset uint32) string {
+	keyLen := uint32(binary.LittleEndian.Uint16(s.buf[offset+20:]))
+	keyStart := offset + entryHeaderSize
+	return string(s.buf[keyStart : keyStart+keyLen])
+}
+
+func (s *ringShard) expiresAt(offset uint32) int64 {
+	return int64(binary.LittleEndian.Uint64(s.buf[offset+4:]))
+}
+
+func (s *ringShard) sweepExpired(now time.Time) int {
+	swept := 0
+	for hash, offset := range s.index {
+		if isExpired(s.expiresAt(offset), now) {
+			delete(s.index, hash)
+			swept++
+		}
+	}
+	return swept
+}
+
+func (s *ringShard) liveKeys(now time.Time) []string {
+	keys := make([]string, 0, len(s.index))
+	for _, offset := range s.index {
+		if !isExpired(s.expiresAt(offset), now) {
+			keys = append(keys, s.keyAt(offset))
+		}
+	}
+	return keys
+}
+
+// deleteMatching drops the index entries whose key matches; their bytes stay
+// in the ring as dead space.
+func (s *ringShard) deleteMatching(match func(key string) bool) {
+	for hash, offset := range s.index {
+		if match(s.keyAt(offset)) {
+			delete(s.index, hash)
+		}
+	}
+}
+
+func (s *ringShard) clear() {
+	s.index = make(map[uint64]uint32)
+	s.head, s.tail, s.wrapAt, s.records, s.wrapped = 0, 0, 0, 0, false
+}
+
+func isExpired(expiresAt int64, now time.Time) bool {
+	return expiresAt != 0 && now.UnixNano() > expiresAt
+}
@@ -0,0 +1,122 @@
+package offheap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRingShardSetAndGet(t *testing.T) {
+	shard := newRingShard(1024)
+
+	if err := shard.set("key1", hashKey("key1"), []byte("value1"), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, found := shard.get("key1", hashKey("key1"), time.Now())
+	if !found || string(value) != "value1" {
+		t.Errorf("expected value1, got %q found=%v", value, found)
+	}
+
+	if _, found = shard.get("missing", hashKey("missing"), time.Now()); found {
+		t.Error("expected missing key to not be found")
+	}
+}
+
+func TestRingShardOverwrite(t *testing.T) {
+	shard := newRingShard(1024)
+
+	_ = shard.set("key1", hashKey("key1"), []byte("value1"), 0)
+	_ = shard.set("key1", hashKey("key1"), []byte("value2"), 0)
+
+	value, found := shard.get("key1", hashKey("key1"), time.Now())
+	if !found || string(value) != "value2" {
+		t.Errorf("expected value2, got %q", value)
+	}
+	if len(shard.index) != 1 {
+		t.Errorf("expected one indexed entry, got %d", len(shard.index))
+	}
+}
+
+func TestRingShardExpiration(t *testing.T) {
+	shard := newRingShard(1024)
+	now := time.Now()
+
+	_ = shard.set("expired", hashKey("expired"), []byte("v"), now.Add(-time.Second).UnixNano())
+	_ = shard.set("live", hashKey("live"), []byte("v"), now.Add(time.Hour).UnixNano())
+
+	if _, found := shard.get("expired", hashKey("expired"), now); found {
+		t.Error("expected expired entry to be hidden")
+	}
+	if keys := shard.liveKeys(now); len(keys) != 1 || keys[0] != "live" {
+		t.Errorf("unexpected live keys: %v", keys)
+	}
+	if swept := shard.sweepExpired(now); swept != 1 {
+		t.Errorf("expected 1 swept entry, got %d", swept)
+	}
+}
+
+func TestRingShardDelete(t *testing.T) {
+	shard := newRingShard(1024)
+
+	_ = shard.set("key1", hashKey("key1"), []byte("value1"), 0)
+	shard.delete("key1", hashKey("key1"))
+
+	if _, found := shard.get("key1", hashKey("key1"), time.Now()); found {
+		t.Error("expected key1 to be deleted")
+	}
+}
+
+func TestRingShardWrapsAndEvictsOldest(t *testing.T) {
+	value := make([]byte, 50)
+	entrySize := entryHeaderSize + len("key00") + len(value)
+	shard := newRingShard(entrySize*4 + 10)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%02d", i)
+		copy(value, key)
+		if err := shard.set(key, hashKey(key), value, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("key%02d", i)
+		if _, found := shard.get(key, hashKey(key), time.Now()); found {
+			t.Errorf("expected %s to be evicted", key)
+		}
+	}
+	for i := 6; i < 10; i++ {
+		key := fmt.Sprintf("key%02d", i)
+		stored, found := shard.get(key, hashKey(key), time.Now())
+		if !found {
+			t.Errorf("expected %s to remain", key)
+			continue
+		}
+		if string(stored[:len(key)]) != key {
+			t.Errorf("expected payload of %s, got %q", key, stored[:len(key)])
+		}
+	}
+}
+
+func TestRingShardRejectsOversizedEntry(t *testing.T) {
+	shard := newRingShard(64)
+
+	if err := shard.set("key1", hashKey("key1"), make([]byte, 128), 0); err == nil {
+		t.Error("expected error for entry larger than shard")
+	}
+}
+
+func TestRingShardClear(t *testing.T) {
+	shard := newRingShard(1024)
+
+	_ = shard.set("key1", hashKey("key1"), []byte("value1"), 0)
+	shard.clear()
+
+	if len(shard.index) != 0 || shard.records != 0 {
+		t.Error("expected empty shard after clear")
+	}
+	if err := shard.set("key2", hashKey("key2"), []byte("value2"), 0); err != nil {
+		t.Fatalf("unexpected error after clear: %v", err)
+	}
+}
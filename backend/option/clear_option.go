@@ -1,22 +1,49 @@
 package option
 
+import "strings"
+
+// NamespaceSeparator ends a key's namespace: "user:42" is in namespace "user".
+const NamespaceSeparator = ":"
+
 type ClrOptFnc func(*ClearConfig)
 
 type ClearConfig struct {
+	Namespace           string
+	Delivery            Delivery
 	PublishInvalidation bool
 }
 
 func WithClearInvalidation() ClrOptFnc {
+	return WithClearDelivery(DeliveryAsync)
+}
+
+func WithClearDelivery(delivery Delivery) ClrOptFnc {
+	return func(cfg *ClearConfig) {
+		cfg.Delivery = delivery
+		cfg.PublishInvalidation = delivery.publishes()
+	}
+}
+
+// WithNamespace clears only the keys in namespace, that is the keys starting
+// with namespace followed by NamespaceSeparator. Namespaces may be nested:
+// clearing "tenant:7" also clears "tenant:7:user:42".
+func WithNamespace(namespace string) ClrOptFnc {
 	return func(cfg *ClearConfig) {
-		cfg.PublishInvalidation = true
+		cfg.Namespace = namespace
 	}
 }
 
+// Matches reports whether the clear covers key.
+func (cfg ClearConfig) Matches(key string) bool {
+	return cfg.Namespace == "" || strings.HasPrefix(key, cfg.Namespace+NamespaceSeparator)
+}
+
 func ApplyClearOptions(options []ClrOptFnc) ClearConfig {
 	cfg := defaultClearConfig()
 	for _, opt := range options {
 		opt(&cfg)
 	}
+	cfg.Delivery = cfg.Delivery.orPublish(cfg.PublishInvalidation)
 	return cfg
 }
 
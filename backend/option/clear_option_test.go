@@ -47,3 +47,15 @@ func TestApplyClearOptionsMultipleInvalidationCalls(t *testing.T) {
 		t.Error("expected PublishInvalidation true")
 	}
 }
+
+func TestWithNamespaceMatches(t *testing.T) {
+	cfg := ApplyClearOptions([]ClrOptFnc{WithNamespace("user")})
+	for key, want := range map[string]bool{"user:1": true, "user:1:profile": true, "users:1": false, "user": false} {
+		if got := cfg.Matches(key); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", key, got, want)
+		}
+	}
+	if !defaultClearConfig().Matches("anything") {
+		t.Error("expected a clear without namespace to match every key")
+	}
+}
@@ -3,12 +3,18 @@ package option
 type DelOptFnc func(*DeleteConfig)
 
 type DeleteConfig struct {
+	Delivery            Delivery
 	PublishInvalidation bool
 }
 
 func WithDeleteInvalidation() DelOptFnc {
+	return WithDeleteDelivery(DeliveryAsync)
+}
+
+func WithDeleteDelivery(delivery Delivery) DelOptFnc {
 	return func(cfg *DeleteConfig) {
-		cfg.PublishInvalidation = true
+		cfg.Delivery = delivery
+		cfg.PublishInvalidation = delivery.publishes()
 	}
 }
 
@@ -17,6 +23,7 @@ func ApplyDeleteOptions(options []DelOptFnc) DeleteConfig {
 	for _, opt := range options {
 		opt(&cfg)
 	}
+	cfg.Delivery = cfg.Delivery.orPublish(cfg.PublishInvalidation)
 	return cfg
 }
 
@@ -0,0 +1,80 @@
+package option
+
+import "fmt"
+
+// Delivery decides how a write's invalidation reaches other nodes.
+type Delivery int
+
+const (
+	// DeliveryDefault defers to the cache-level default.
+	DeliveryDefault Delivery = iota
+	// DeliveryLocal changes only the local cache.
+	DeliveryLocal
+	// DeliveryAsync queues the invalidation and returns without waiting for it.
+	DeliveryAsync
+	// DeliverySync publishes after the local change, directly rather than
+	// through the queue, and returns once the publish is done. A failed
+	// publish is returned, but the local change has already been applied and
+	// stands; only peers may be left stale.
+	DeliverySync
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case DeliveryDefault:
+		return "default"
+	case DeliveryLocal:
+		return "local"
+	case DeliveryAsync:
+		return "async"
+	case DeliverySync:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+// Or returns d, or fallback when d is DeliveryDefault.
+func (d Delivery) Or(fallback Delivery) Delivery {
+	if d == DeliveryDefault {
+		return fallback
+	}
+	return d
+}
+
+// orPublish maps the legacy PublishInvalidation flag onto an unset delivery.
+// SetConfig, DeleteConfig and ClearConfig keep the flag for options that set
+// it directly; without a Delivery it means DeliveryAsync.
+func (d Delivery) orPublish(publish bool) Delivery {
+	if publish {
+		return d.Or(DeliveryAsync)
+	}
+	return d
+}
+
+func (d Delivery) publishes() bool {
+	return d == DeliveryAsync || d == DeliverySync
+}
+
+func ParseDelivery(s string) (Delivery, error) {
+	switch s {
+	case "":
+		return DeliveryDefault, nil
+	case "local":
+		return DeliveryLocal, nil
+	case "async":
+		return DeliveryAsync, nil
+	case "sync":
+		return DeliverySync, nil
+	default:
+		return DeliveryDefault, fmt.Errorf("unknown delivery %q, expected local, async or sync", s)
+	}
+}
+
+// DeliveryDefaults are the deliveries a cache uses for calls that do not
+// choose one.
+type DeliveryDefaults struct {
+	Set    Delivery
+	Delete Delivery
+	Clear  Delivery
+}
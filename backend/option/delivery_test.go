@@ -0,0 +1,52 @@
+package option
+
+import "testing"
+
+func TestParseDelivery(t *testing.T) {
+	for input, want := range map[string]Delivery{"": DeliveryDefault, "local": DeliveryLocal, "async": DeliveryAsync, "sync": DeliverySync} {
+		got, err := ParseDelivery(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDelivery(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseDelivery("eventually"); err == nil {
+		t.Error("expected error for unknown delivery")
+	}
+}
+
+func TestDeliveryOr(t *testing.T) {
+	if got := DeliveryDefault.Or(DeliveryAsync); got != DeliveryAsync {
+		t.Errorf("expected fallback, got %v", got)
+	}
+	if got := DeliveryLocal.Or(DeliveryAsync); got != DeliveryLocal {
+		t.Errorf("expected explicit delivery to win, got %v", got)
+	}
+}
+
+func TestWithDelivery(t *testing.T) {
+	cfg := ApplyOptions([]OptFnc{WithDelivery(DeliverySync)})
+	if cfg.Delivery != DeliverySync || !cfg.PublishInvalidation {
+		t.Errorf("unexpected set config %+v", cfg)
+	}
+	deleteCfg := ApplyDeleteOptions([]DelOptFnc{WithDeleteInvalidation(), WithDeleteDelivery(DeliveryLocal)})
+	if deleteCfg.Delivery != DeliveryLocal || deleteCfg.PublishInvalidation {
+		t.Errorf("expected the last delivery to win, got %+v", deleteCfg)
+	}
+	clearCfg := ApplyClearOptions([]ClrOptFnc{WithClearInvalidation()})
+	if clearCfg.Delivery != DeliveryAsync {
+		t.Errorf("expected WithClearInvalidation to be async, got %v", clearCfg.Delivery)
+	}
+}
+
+func TestPublishInvalidationFlagMeansAsync(t *testing.T) {
+	legacy := func(cfg *SetConfig) { cfg.PublishInvalidation = true }
+	if cfg := ApplyOptions([]OptFnc{legacy}); cfg.Delivery != DeliveryAsync {
+		t.Errorf("expected a directly set PublishInvalidation to publish async, got %v", cfg.Delivery)
+	}
+	if cfg := ApplyOptions([]OptFnc{WithDelivery(DeliverySync)}); cfg.Delivery != DeliverySync {
+		t.Errorf("expected an explicit delivery to win, got %v", cfg.Delivery)
+	}
+	if cfg := ApplyDeleteOptions(nil); cfg.Delivery != DeliveryDefault {
+		t.Errorf("expected no options to leave the delivery unset, got %v", cfg.Delivery)
+	}
+}
@@ -6,6 +6,9 @@ type OptFnc func(*SetConfig)
 
 type SetConfig struct {
 	TTL                 time.Duration
+	Delivery            Delivery
+	Epoch               uint64
+	Stamp               int64
 	PublishInvalidation bool
 	NoExpiration        bool
 }
@@ -17,9 +20,34 @@ func WithTTL(ttl time.Duration) OptFnc {
 	}
 }
 
+// WithInvalidation queues an invalidation for peers; it is the same as
+// WithDelivery(DeliveryAsync).
 func WithInvalidation() OptFnc {
+	return WithDelivery(DeliveryAsync)
+}
+
+func WithDelivery(delivery Delivery) OptFnc {
+	return func(cfg *SetConfig) {
+		cfg.Delivery = delivery
+		cfg.PublishInvalidation = delivery.publishes()
+	}
+}
+
+// WithEpoch refuses the write if the cache has been cleared since epoch was
+// read from it. Zero leaves the write untagged.
+func WithEpoch(epoch uint64) OptFnc {
+	return func(cfg *SetConfig) {
+		cfg.Epoch = epoch
+	}
+}
+
+// WithStamp records stamp as the write's invalidation stamp instead of a
+// fresh one. A copy of a value read elsewhere should carry the stamp taken
+// before that read, so invalidations of older writes still remove it. Zero
+// leaves the write to be stamped as usual.
+func WithStamp(stamp int64) OptFnc {
 	return func(cfg *SetConfig) {
-		cfg.PublishInvalidation = true
+		cfg.Stamp = stamp
 	}
 }
 
@@ -35,6 +63,7 @@ func ApplyOptions(options []OptFnc) SetConfig {
 	for _, opt := range options {
 		opt(&cfg)
 	}
+	cfg.Delivery = cfg.Delivery.orPublish(cfg.PublishInvalidation)
 	return cfg
 }
 
@@ -17,6 +17,10 @@ func TestDefaultSetConfig(t *testing.T) {
 	if cfg.NoExpiration != false {
 		t.Error("expected NoExpiration false")
 	}
+	// The in-memory backend relies on the defaults being the zero configs.
+	if ApplyOptions(nil) != (SetConfig{}) || ApplyDeleteOptions(nil) != (DeleteConfig{}) {
+		t.Error("expected the defaults to be the zero configs")
+	}
 }
 
 func TestWithTTL(t *testing.T) {
@@ -0,0 +1,336 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/codec"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+	"github.com/halilbulentorhon/invacache-go/pkg/singleflight"
+)
+
+type remoteBackend[V any] struct {
+	ctx              context.Context
+	store            Store
+	codec            codec.Codec[V]
+	logger           logger.Logger
+	cancel           context.CancelFunc
+	singleFlight     singleflight.Group[V]
+	defaultTTL       time.Duration
+	lockTTL          time.Duration
+	lockWait         time.Duration
+	operationTimeout time.Duration
+}
+
+func (r *remoteBackend[V]) Get(key string) (V, error) {
+	value, found, err := r.get(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	if !found {
+		var zero V
+		return zero, constant.ErrNotFound
+	}
+	return value, nil
+}
+
+func (r *remoteBackend[V]) get(key string) (V, bool, error) {
+	ctx, cancel := r.operationContext()
+	defer cancel()
+
+	var zero V
+	data, found, err := r.store.Get(ctx, key)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to get key %s from remote store: %w", key, err)
+	}
+	if !found {
+		return zero, false, nil
+	}
+
+	value, err := r.codec.Unmarshal(data)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to decode value for key %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (r *remoteBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V]) (V, error) {
+	value, found, err := r.get(key)
+	if err == nil && found {
+		return value, nil
+	}
+	if err != nil {
+		r.logger.Warn("remote get failed, falling back to loader", "key", key, "error", err)
+	}
+
+	value, _, err = r.singleFlight.Do(key, func() (V, time.Duration, error) {
+		return r.loadWithLock(key, loader)
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return value, nil
+}
+
+func (r *remoteBackend[V]) loadWithLock(key string, loader backend.LoaderFunc[V]) (V, time.Duration, error) {
+	ctx, cancel := r.operationContext()
+	unlock, acquired, err := r.store.TryLock(ctx, key, r.lockTTL)
+	cancel()
+	if err != nil {
+		r.logger.Warn("failed to acquire load lock, loading without it", "key", key, "error", err)
+		return r.load(key, loader)
+	}
+
+	if acquired {
+		defer func() {
+			ctx, cancel := r.operationContext()
+			defer cancel()
+			if unlockErr := unlock(ctx); unlockErr != nil {
+				r.logger.Warn("failed to release load lock", "key", key, "error", unlockErr)
+			}
+		}()
+
+		if value, found, getErr := r.get(key); getErr == nil && found {
+			return value, 0, nil
+		}
+		return r.load(key, loader)
+	}
+
+	deadline := time.Now().Add(r.lockWait)
+	for time.Now().Before(deadline) {
+		select {
+		case <-r.ctx.Done():
+			var zero V
+			return zero, 0, r.ctx.Err()
+		case <-time.After(constant.DefaultLockPollInterval):
+		}
+
+		if value, found, getErr := r.get(key); getErr == nil && found {
+			return value, 0, nil
+		}
+	}
+
+	r.logger.Warn("timed out waiting for load lock holder", "key", key)
+	return r.load(key, loader)
+}
+
+func (r *remoteBackend[V]) load(key string, loader backend.LoaderFunc[V]) (V, time.Duration, error) {
+	value, ttl, err := loader(key)
+	if err != nil {
+		var zero V
+		return zero, 0, err
+	}
+
+	if setErr := r.Set(key, value, option.WithTTL(ttl)); setErr != nil {
+		r.logger.Warn("failed to store loaded value", "key", key, "error", setErr)
+	}
+	return value, ttl, nil
+}
+
+func (r *remoteBackend[V]) Set(key string, value V, options ...option.OptFnc) error {
+	cfg := option.ApplyOptions(options)
+
+	var ttl time.Duration
+	if !cfg.NoExpiration {
+		ttl = cfg.TTL
+		if ttl == 0 {
+			ttl = r.defaultTTL
+		}
+	}
+
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for key %s: %w", key, err)
+	}
+
+	ctx, cancel := r.operationContext()
+	defer cancel()
+
+	if err = r.store.Set(ctx, key, data, ttl); err != nil {
+		return fmt.Errorf("failed to set key %s in remote store: %w", key, err)
+	}
+	return nil
+}
+
+func (r *remoteBackend[V]) Delete(key string, _ ...option.DelOptFnc) error {
+	return r.delete(key)
+}
+
+func (r *remoteBackend[V]) delete(key string) error {
+	ctx, cancel := r.operationContext()
+	defer cancel()
+
+	if err := r.store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete key %s from remote store: %w", key, err)
+	}
+	return nil
+}
+
+// Clear uses the store's BatchClearer when it has one, with each batch under
+// its own operation timeout. Otherwise a namespace Clear uses the store's
+// PrefixClearer, or lists the store's keys and deletes the matching ones, each
+// delete under its own operation timeout.
+func (r *remoteBackend[V]) Clear(options ...option.ClrOptFnc) error {
+	cfg := option.ApplyClearOptions(options)
+	if clearer, ok := r.store.(BatchClearer); ok {
+		prefix := ""
+		if cfg.Namespace != "" {
+			prefix = cfg.Namespace + option.NamespaceSeparator
+		}
+		if err := clearer.ClearBatches(r.ctx, prefix, r.operationTimeout); err != nil {
+			return fmt.Errorf("failed to clear remote store: %w", err)
+		}
+		return nil
+	}
+
+	if cfg.Namespace == "" {
+		ctx, cancel := r.operationContext()
+		defer cancel()
+		if err := r.store.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to clear remote store: %w", err)
+		}
+		return nil
+	}
+
+	if clearer, ok := r.store.(PrefixClearer); ok {
+		ctx, cancel := r.operationContext()
+		defer cancel()
+		if err := clearer.ClearPrefix(ctx, cfg.Namespace+option.NamespaceSeparator); err != nil {
+			return fmt.Errorf("failed to clear namespace %s: %w", cfg.Namespace, err)
+		}
+		return nil
+	}
+
+	ctx, cancel := r.operationContext()
+	keys, err := r.store.Keys(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to list keys in namespace %s: %w", cfg.Namespace, err)
+	}
+	for _, key := range keys {
+		if !cfg.Matches(key) {
+			continue
+		}
+		if err := r.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *remoteBackend[V]) Len() int {
+	return len(r.Keys())
+}
+
+func (r *remoteBackend[V]) Keys() []string {
+	ctx, cancel := r.operationContext()
+	defer cancel()
+
+	keys, err := r.store.Keys(ctx)
+	if err != nil {
+		r.logger.Warn("failed to list remote keys", "error", err)
+	}
+	return keys
+}
+
+func (r *remoteBackend[V]) Range(fn func(key string, value V) bool) {
+	for _, key := range r.Keys() {
+		value, found, err := r.get(key)
+		if err != nil {
+			r.logger.Warn("failed to read remote key during range", "key", key, "error", err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+func (r *remoteBackend[V]) Close() error {
+	r.logger.Info("closing remote cache")
+	r.cancel()
+	if err := r.store.Close(); err != nil {
+		r.logger.Error("failed to close remote store", "error", err)
+		return err
+	}
+	r.logger.Info("remote cache closed successfully")
+	return nil
+}
+
+func (r *remoteBackend[V]) operationContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.ctx, r.operationTimeout)
+}
+
+func NewRemoteBackend[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.Remote == nil {
+		cfg.Backend.Remote = &config.RemoteConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(cfg.BackendName, cfg.Backend.Remote.DriverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote store: %w", err)
+	}
+
+	be, err := NewRemoteBackendWithStore[V](store, cfg)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return be, nil
+}
+
+func NewRemoteBackendWithStore[V any](store Store, cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	log := cfg.NewLogger("remote-cache")
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.Remote == nil {
+		cfg.Backend.Remote = &config.RemoteConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	valueCodec, err := codec.New[V](cfg.Codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create codec: %w", err)
+	}
+
+	remoteCfg := cfg.Backend.Remote
+	log.Info("initializing remote cache",
+		"store", cfg.BackendName,
+		"lock_ttl", remoteCfg.LockTTL,
+		"operation_timeout", remoteCfg.OperationTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &remoteBackend[V]{
+		ctx:              ctx,
+		store:            store,
+		codec:            valueCodec,
+		logger:           log,
+		cancel:           cancel,
+		singleFlight:     singleflight.Group[V]{},
+		defaultTTL:       remoteCfg.DefaultTTL,
+		lockTTL:          remoteCfg.LockTTL,
+		lockWait:         remoteCfg.LockWait,
+		operationTimeout: remoteCfg.OperationTimeout,
+	}, nil
+}
@@ -0,0 +1,413 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+type memItem struct {
+	expiresAt time.Time
+	value     []byte
+}
+
+type memStore struct {
+	items  map[string]memItem
+	locks  map[string]bool
+	closed bool
+	mu     sync.Mutex
+}
+
+func newMemStore() *memStore {
+	return &memStore{items: make(map[string]memItem), locks: make(map[string]bool)}
+}
+
+func (m *memStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || (!item.expiresAt.IsZero() && time.Now().After(item.expiresAt)) {
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (m *memStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := memItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	m.items[key] = item
+	return nil
+}
+
+func (m *memStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+func (m *memStore) Clear(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = make(map[string]memItem)
+	return nil
+}
+
+func (m *memStore) Keys(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.items))
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (m *memStore) TryLock(_ context.Context, key string, _ time.Duration) (UnlockFunc, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks[key] {
+		return nil, false, nil
+	}
+	m.locks[key] = true
+	return func(context.Context) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.locks, key)
+		return nil
+	}, true, nil
+}
+
+func (m *memStore) Close() error {
+	m.closed = true
+	return nil
+}
+
+func createTestCache[V any](t *testing.T, store Store) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			Remote: &config.RemoteConfig{
+				LockWait: 500 * time.Millisecond,
+			},
+		},
+	}
+
+	cache, err := NewRemoteBackendWithStore[V](store, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error creating cache: %v", err)
+	}
+	return cache
+}
+
+func TestRemoteSetAndGet(t *testing.T) {
+	cache := createTestCache[string](t, newMemStore())
+	defer cache.Close()
+
+	if err := cache.Set("key1", "value1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := cache.Get("key1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("expected 'value1', got '%s'", value)
+	}
+}
+
+func TestRemoteGetNonExistentKey(t *testing.T) {
+	cache := createTestCache[string](t, newMemStore())
+	defer cache.Close()
+
+	_, err := cache.Get("missing")
+	if err == nil {
+		t.Fatal("expected error for missing key")
+	}
+	if err != constant.ErrNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRemoteSetWithTTL(t *testing.T) {
+	store := newMemStore()
+	cache := createTestCache[string](t, store)
+	defer cache.Close()
+
+	_ = cache.Set("key1", "value1", option.WithTTL(50*time.Millisecond))
+	_ = cache.Set("key2", "value2", option.WithNoExpiration())
+
+	if store.items["key1"].expiresAt.IsZero() {
+		t.Error("expected key1 to have an expiration")
+	}
+	if !store.items["key2"].expiresAt.IsZero() {
+		t.Error("expected key2 to have no expiration")
+	}
+}
+
+func TestRemoteDeleteAndClear(t *testing.T) {
+	cache := createTestCache[int](t, newMemStore())
+	defer cache.Close()
+
+	_ = cache.Set("a", 1)
+	_ = cache.Set("b", 2)
+	_ = cache.Set("c", 3)
+
+	if err := cache.Delete("a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := cache.Get("a"); err == nil {
+		t.Error("expected a to be deleted")
+	}
+
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	sum := 0
+	cache.Range(func(_ string, value int) bool {
+		sum += value
+		return true
+	})
+	if sum != 5 {
+		t.Errorf("expected sum 5, got %d", sum)
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected empty cache, got %d", cache.Len())
+	}
+}
+
+// prefixStore is a memStore that clears namespaces itself.
+type prefixStore struct {
+	*memStore
+	prefixes []string
+}
+
+func (p *prefixStore) ClearPrefix(_ context.Context, prefix string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prefixes = append(p.prefixes, prefix)
+	for key := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.items, key)
+		}
+	}
+	return nil
+}
+
+func TestRemoteClearNamespace(t *testing.T) {
+	prefixed := &prefixStore{memStore: newMemStore()}
+	for _, store := range []Store{newMemStore(), prefixed} {
+		cache := createTestCache[int](t, store)
+		_ = cache.Set("user:1", 1)
+		_ = cache.Set("user:2", 2)
+		_ = cache.Set("username", 3)
+
+		if err := cache.Clear(option.WithNamespace("user")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if keys := cache.Keys(); len(keys) != 1 || keys[0] != "username" {
+			t.Errorf("%T: expected only username to remain, got %v", store, keys)
+		}
+		_ = cache.Close()
+	}
+	if len(prefixed.prefixes) != 1 || prefixed.prefixes[0] != "user:" {
+		t.Errorf("expected the namespace clear to use ClearPrefix, got %v", prefixed.prefixes)
+	}
+}
+
+// batchStore is a prefixStore that also clears in batches.
+type batchStore struct {
+	*prefixStore
+	timeouts []time.Duration
+	deadline bool
+}
+
+func (b *batchStore) ClearBatches(ctx context.Context, prefix string, batchTimeout time.Duration) error {
+	_, b.deadline = ctx.Deadline()
+	b.timeouts = append(b.timeouts, batchTimeout)
+	return b.ClearPrefix(ctx, prefix)
+}
+
+func TestRemoteClearUsesBatchClearer(t *testing.T) {
+	store := &batchStore{prefixStore: &prefixStore{memStore: newMemStore()}}
+	cache := createTestCache[int](t, store)
+	defer cache.Close()
+	_ = cache.Set("user:1", 1)
+	_ = cache.Set("order:1", 2)
+
+	if err := cache.Clear(option.WithNamespace("user")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys := cache.Keys(); len(keys) != 1 || keys[0] != "order:1" {
+		t.Errorf("expected only order:1 to remain, got %v", keys)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected empty cache, got %d", cache.Len())
+	}
+
+	if len(store.prefixes) != 2 || store.prefixes[0] != "user:" || store.prefixes[1] != "" {
+		t.Errorf("expected both clears to go through ClearBatches, got %v", store.prefixes)
+	}
+	if store.deadline || store.timeouts[0] != constant.DefaultOperationTimeout {
+		t.Errorf("expected the operation timeout per batch and none overall, got %v, deadline %v", store.timeouts, store.deadline)
+	}
+}
+
+func TestRemoteGetOrLoad(t *testing.T) {
+	cache := createTestCache[string](t, newMemStore())
+	defer cache.Close()
+
+	value, err := cache.GetOrLoad("key1", func(key string) (string, time.Duration, error) {
+		return "loaded-" + key, time.Minute, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "loaded-key1" {
+		t.Errorf("unexpected value: %s", value)
+	}
+
+	value, err = cache.GetOrLoad("key1", func(key string) (string, time.Duration, error) {
+		return "", 0, errors.New("loader should not be called")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "loaded-key1" {
+		t.Errorf("unexpected value: %s", value)
+	}
+}
+
+func TestRemoteGetOrLoadLoaderError(t *testing.T) {
+	cache := createTestCache[string](t, newMemStore())
+	defer cache.Close()
+
+	_, err := cache.GetOrLoad("key1", func(key string) (string, time.Duration, error) {
+		return "", 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected loader error")
+	}
+	if _, err = cache.Get("key1"); err == nil {
+		t.Error("failed load should not be cached")
+	}
+}
+
+func TestRemoteGetOrLoadAcrossInstances(t *testing.T) {
+	store := newMemStore()
+	first := createTestCache[string](t, store)
+	defer first.Close()
+	second := createTestCache[string](t, store)
+	defer second.Close()
+
+	var calls int32
+	loader := func(key string) (string, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "value", time.Minute, nil
+	}
+
+	var wg sync.WaitGroup
+	for _, cache := range []backend.Cache[string]{first, second, first, second} {
+		wg.Add(1)
+		go func(cache backend.Cache[string]) {
+			defer wg.Done()
+			value, err := cache.GetOrLoad("shared", loader)
+			if err != nil || value != "value" {
+				t.Errorf("unexpected result: %q, %v", value, err)
+			}
+		}(cache)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("expected loader to run once across instances, ran %d times", got)
+	}
+}
+
+func TestRemoteGetOrLoadLockTimeout(t *testing.T) {
+	store := newMemStore()
+	store.locks["stuck"] = true
+
+	cache := createTestCache[string](t, store)
+	defer cache.Close()
+
+	value, err := cache.GetOrLoad("stuck", func(key string) (string, time.Duration, error) {
+		return "fallback", time.Minute, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "fallback" {
+		t.Errorf("expected fallback load after lock wait, got %s", value)
+	}
+}
+
+func TestRemoteClose(t *testing.T) {
+	store := newMemStore()
+	cache := createTestCache[string](t, store)
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !store.closed {
+		t.Error("expected store to be closed")
+	}
+}
+
+func TestNewStoreRegistry(t *testing.T) {
+	// Backend names cannot be unregistered, so every run needs its own.
+	name := fmt.Sprintf("mem-%d", time.Now().UnixNano())
+	if _, err := NewStore(name, nil); err == nil {
+		t.Error("expected error for unknown store")
+	}
+
+	if err := RegisterStore(name, func(map[string]any) (Store, error) {
+		return newMemStore(), nil
+	}); err != nil {
+		t.Fatalf("unexpected error registering store: %v", err)
+	}
+	if !IsRegistered(name) {
+		t.Error("expected the store to be registered")
+	}
+	if err := RegisterStore(name, func(map[string]any) (Store, error) {
+		return newMemStore(), nil
+	}); err == nil {
+		t.Error("expected registering the same name twice to fail")
+	}
+
+	cache, err := NewRemoteBackend[string](config.InvaCacheConfig{BackendName: name})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	if err = cache.Set("key1", "value1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/pkg/decode"
+)
+
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Clear(ctx context.Context) error
+	Keys(ctx context.Context) ([]string, error)
+	TryLock(ctx context.Context, key string, ttl time.Duration) (UnlockFunc, bool, error)
+	Close() error
+}
+
+type UnlockFunc func(ctx context.Context) error
+
+// PrefixClearer is implemented by stores that can delete every key starting
+// with a prefix on the server side. A namespace Clear uses it instead of
+// listing the store's keys and deleting them one by one.
+type PrefixClearer interface {
+	ClearPrefix(ctx context.Context, prefix string) error
+}
+
+// BatchClearer is implemented by stores that clear in batches, each bounded
+// by batchTimeout. Clear prefers it to Store.Clear and PrefixClearer and passes
+// the operation timeout as batchTimeout, so a large store is not cut short by
+// one timeout for the whole clear. ctx ends only when the cache is closed.
+type BatchClearer interface {
+	ClearBatches(ctx context.Context, prefix string, batchTimeout time.Duration) error
+}
+
+type StoreFactory func(config map[string]any) (Store, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]StoreFactory)
+)
+
+// RegisterStore registers a store and its name as a backend. It is safe for
+// concurrent use and fails if the name is already taken by any backend.
+func RegisterStore(name string, factory StoreFactory) error {
+	if factory == nil {
+		return fmt.Errorf("store %s registration needs a factory", name)
+	}
+	if err := config.RegisterBackend(name); err != nil {
+		return err
+	}
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+	return nil
+}
+
+// RegisterTypedStore registers a store whose DriverConfig is decoded into C
+// with the same coercion and unknown-field rules as invalidation drivers.
+func RegisterTypedStore[C any](name string, factory func(cfg C) (Store, error)) error {
+	return RegisterStore(name, func(config map[string]any) (Store, error) {
+		cfg, err := decode.Config[C](config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s store config: %w", name, err)
+		}
+		return factory(cfg)
+	})
+}
+
+func IsRegistered(name string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, exists := factories[name]
+	return exists
+}
+
+func NewStore(storeType string, config map[string]any) (Store, error) {
+	factoriesMu.RLock()
+	factory, exists := factories[storeType]
+	factoriesMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown remote store type: %s", storeType)
+	}
+	return factory(config)
+}
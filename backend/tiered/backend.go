@@ -0,0 +1,227 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/inmemory"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/backend/remote"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type tieredBackend[V any] struct {
+	l1       backend.Cache[V]
+	l2       backend.Cache[V]
+	logger   logger.Logger
+	delivery option.DeliveryDefaults
+	l1TTL    time.Duration
+}
+
+func (t *tieredBackend[V]) Get(key string) (V, error) {
+	if value, err := t.l1.Get(key); err == nil {
+		return value, nil
+	}
+
+	epoch, stamp := t.Epoch(), t.stamp()
+	value, err := t.l2.Get(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	t.backfill(key, value, 0, epoch, stamp)
+	return value, nil
+}
+
+func (t *tieredBackend[V]) GetOrLoad(key string, loader backend.LoaderFunc[V]) (V, error) {
+	if value, err := t.l1.Get(key); err == nil {
+		return value, nil
+	}
+
+	epoch, stamp := t.Epoch(), t.stamp()
+	var loadedTTL time.Duration
+	value, err := t.l2.GetOrLoad(key, func(key string) (V, time.Duration, error) {
+		value, ttl, err := loader(key)
+		loadedTTL = ttl
+		return value, ttl, err
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	t.backfill(key, value, loadedTTL, epoch, stamp)
+	return value, nil
+}
+
+// backfill stores a value read from l2 in l1, unless l1 was cleared since
+// epoch was read: the value may predate the clear. It carries the stamp taken
+// before the read, so a peer invalidation of an earlier write that arrives
+// afterwards still removes it.
+func (t *tieredBackend[V]) backfill(key string, value V, ttl time.Duration, epoch uint64, stamp int64) {
+	err := t.l1.Set(key, value, option.WithTTL(t.nearTTL(ttl)), option.WithDelivery(option.DeliveryLocal), option.WithEpoch(epoch), option.WithStamp(stamp))
+	if err != nil && !errors.Is(err, constant.ErrStaleEpoch) {
+		t.logger.Warn("failed to back-fill l1", "key", key, "error", err)
+	}
+}
+
+// Epoch reports l1's epoch, which every clear reaching this node advances.
+func (t *tieredBackend[V]) Epoch() uint64 {
+	if reporter, ok := t.l1.(backend.EpochReporter); ok {
+		return reporter.Epoch()
+	}
+	return 0
+}
+
+func (t *tieredBackend[V]) stamp() int64 {
+	if stamper, ok := t.l1.(backend.Stamper); ok {
+		return stamper.Stamp()
+	}
+	return 0
+}
+
+func (t *tieredBackend[V]) nearTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < t.l1TTL {
+		return ttl
+	}
+	return t.l1TTL
+}
+
+func (t *tieredBackend[V]) Set(key string, value V, options ...option.OptFnc) error {
+	cfg := option.ApplyOptions(options)
+	// Checked up front as well, so a stale write does not reach l2.
+	if cfg.Epoch != 0 && cfg.Epoch < t.Epoch() {
+		return fmt.Errorf("%w: %s", constant.ErrStaleEpoch, key)
+	}
+	if err := t.l2.Set(key, value, options...); err != nil {
+		return err
+	}
+
+	ttl := cfg.TTL
+	if cfg.NoExpiration {
+		ttl = 0
+	}
+	return t.l1.Set(key, value, option.WithTTL(t.nearTTL(ttl)), option.WithDelivery(cfg.Delivery.Or(t.delivery.Set)), option.WithEpoch(cfg.Epoch))
+}
+
+func (t *tieredBackend[V]) Delete(key string, options ...option.DelOptFnc) error {
+	if err := t.l2.Delete(key, options...); err != nil {
+		return err
+	}
+	cfg := option.ApplyDeleteOptions(options)
+	return t.l1.Delete(key, option.WithDeleteDelivery(cfg.Delivery.Or(t.delivery.Delete)))
+}
+
+func (t *tieredBackend[V]) Clear(options ...option.ClrOptFnc) error {
+	if err := t.l2.Clear(options...); err != nil {
+		return err
+	}
+	cfg := option.ApplyClearOptions(options)
+	return t.l1.Clear(option.WithNamespace(cfg.Namespace), option.WithClearDelivery(cfg.Delivery.Or(t.delivery.Clear)))
+}
+
+func (t *tieredBackend[V]) Len() int {
+	return t.l2.Len()
+}
+
+func (t *tieredBackend[V]) Keys() []string {
+	return t.l2.Keys()
+}
+
+func (t *tieredBackend[V]) Range(fn func(key string, value V) bool) {
+	t.l2.Range(fn)
+}
+
+// Health reports the near cache, which owns the invalidation subscription.
+func (t *tieredBackend[V]) Health() backend.Health {
+	health := backend.Health{Backend: constant.TieredBackend}
+	if reporter, ok := t.l1.(backend.HealthReporter); ok {
+		l1 := reporter.Health()
+		health.Closed = l1.Closed
+		health.Invalidation = l1.Invalidation
+	}
+	return health
+}
+
+func (t *tieredBackend[V]) WaitReady(ctx context.Context) error {
+	if reporter, ok := t.l1.(backend.HealthReporter); ok {
+		return reporter.WaitReady(ctx)
+	}
+	return nil
+}
+
+func (t *tieredBackend[V]) Flush(ctx context.Context) error {
+	if flusher, ok := t.l1.(backend.Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+func (t *tieredBackend[V]) Close() error {
+	t.logger.Info("closing tiered cache")
+	return errors.Join(t.l1.Close(), t.l2.Close())
+}
+
+func NewTieredBackend[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.Tiered == nil {
+		cfg.Backend.Tiered = &config.TieredConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	l2Cfg := cfg
+	l2Cfg.BackendName = cfg.Backend.Tiered.L2Backend
+	l2Cfg.Invalidation = nil
+	l2, err := remote.NewRemoteBackend[V](l2Cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create l2 cache: %w", err)
+	}
+
+	l1, err := inmemory.NewInMemoryBackend[V](cfg)
+	if err != nil {
+		_ = l2.Close()
+		return nil, fmt.Errorf("failed to create l1 cache: %w", err)
+	}
+
+	return NewTieredBackendWithCaches[V](l1, l2, cfg)
+}
+
+func NewTieredBackendWithCaches[V any](l1, l2 backend.Cache[V], cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	log := cfg.NewLogger("tiered-cache")
+	if cfg.Backend == nil {
+		cfg.Backend = &config.BackendConfig{}
+	}
+	if cfg.Backend.Tiered == nil {
+		cfg.Backend.Tiered = &config.TieredConfig{}
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Invalidation == nil {
+		log.Warn("tiered cache has no invalidation configured, l1 copies on other nodes will only expire by ttl")
+	}
+	log.Info("initializing tiered cache", "l2_backend", cfg.Backend.Tiered.L2Backend, "l1_ttl", cfg.Backend.Tiered.L1TTL)
+
+	return &tieredBackend[V]{
+		l1:     l1,
+		l2:     l2,
+		logger: log,
+		// l1 copies on other nodes are stale after any write, so operations the
+		// config leaves unset publish asynchronously.
+		delivery: cfg.Invalidation.DeliveryDefaultsOr(option.DeliveryAsync),
+		l1TTL:    cfg.Backend.Tiered.L1TTL,
+	}, nil
+}
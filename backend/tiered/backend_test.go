@@ -0,0 +1,304 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/inmemory"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type testBus struct {
+	handlers []invalidation.InvalidationHandler
+	mu       sync.Mutex
+}
+
+func (b *testBus) Publish(_ context.Context, key string) error {
+	b.mu.Lock()
+	handlers := append([]invalidation.InvalidationHandler(nil), b.handlers...)
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		_ = handler(key)
+	}
+	return nil
+}
+
+func (b *testBus) Subscribe(ctx context.Context, handler invalidation.InvalidationHandler) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *testBus) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.handlers)
+}
+
+type busClient struct {
+	bus *testBus
+}
+
+func (c busClient) Publish(ctx context.Context, key string) error {
+	return c.bus.Publish(ctx, key)
+}
+
+func (c busClient) Subscribe(ctx context.Context, handler invalidation.InvalidationHandler) error {
+	return c.bus.Subscribe(ctx, handler)
+}
+
+func (c busClient) Close() error {
+	return nil
+}
+
+var bus = &testBus{}
+
+func init() {
+	invalidation.RegisterInvalidatorWithLogger("tiered-test-bus", func(any, logger.Logger) (invalidation.PubSub, error) {
+		return busClient{bus: bus}, nil
+	})
+}
+
+func newL1[V any](t *testing.T, withInvalidation bool) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			InMemory: &config.InMemoryConfig{ShardCount: 2, Capacity: 100, SweeperInterval: time.Minute},
+		},
+	}
+	if withInvalidation {
+		cfg.Invalidation = &config.InvalidationConfig{Type: "tiered-test-bus"}
+	}
+
+	cache, err := inmemory.NewInMemoryBackend[V](cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cache
+}
+
+func newTiered[V any](t *testing.T, l1, l2 backend.Cache[V]) backend.Cache[V] {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{
+			Tiered: &config.TieredConfig{L1Ttl: "1m"},
+		},
+	}
+	cache, err := NewTieredBackendWithCaches[V](l1, l2, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cache
+}
+
+func TestTieredReadThroughAndBackfill(t *testing.T) {
+	l1 := newL1[string](t, false)
+	l2 := newL1[string](t, false)
+	cache := newTiered(t, l1, l2)
+	defer cache.Close()
+
+	_ = l2.Set("key1", "value1")
+
+	value, err := cache.Get("key1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("expected 'value1', got '%s'", value)
+	}
+
+	if value, err = l1.Get("key1"); err != nil || value != "value1" {
+		t.Errorf("expected l1 to be back-filled, got %q, %v", value, err)
+	}
+
+	if _, err = cache.Get("missing"); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestTieredGetOrLoad(t *testing.T) {
+	l1 := newL1[string](t, false)
+	l2 := newL1[string](t, false)
+	cache := newTiered(t, l1, l2)
+	defer cache.Close()
+
+	calls := 0
+	loader := func(key string) (string, time.Duration, error) {
+		calls++
+		return "loaded", 10 * time.Second, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrLoad("key1", loader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value != "loaded" {
+			t.Errorf("expected 'loaded', got '%s'", value)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected loader to be called once, got %d", calls)
+	}
+
+	if _, err := l1.Get("key1"); err != nil {
+		t.Error("expected l1 to hold loaded value")
+	}
+	if _, err := l2.Get("key1"); err != nil {
+		t.Error("expected l2 to hold loaded value")
+	}
+
+	_, err := cache.GetOrLoad("failing", func(string) (string, time.Duration, error) {
+		return "", 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Error("expected loader error")
+	}
+}
+
+func TestTieredWritesBothTiers(t *testing.T) {
+	l1 := newL1[string](t, false)
+	l2 := newL1[string](t, false)
+	cache := newTiered(t, l1, l2)
+	defer cache.Close()
+
+	if err := cache.Set("key1", "value1", option.WithNoExpiration()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := l1.Get("key1"); err != nil {
+		t.Error("expected key in l1")
+	}
+	if _, err := l2.Get("key1"); err != nil {
+		t.Error("expected key in l2")
+	}
+	if cache.Len() != 1 || len(cache.Keys()) != 1 {
+		t.Errorf("expected one key, got %d", cache.Len())
+	}
+
+	if err := cache.Delete("key1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := l1.Get("key1"); err == nil {
+		t.Error("expected key deleted from l1")
+	}
+	if _, err := l2.Get("key1"); err == nil {
+		t.Error("expected key deleted from l2")
+	}
+
+	_ = cache.Set("key2", "value2")
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l1.Len() != 0 || l2.Len() != 0 {
+		t.Error("expected both tiers to be cleared")
+	}
+}
+
+func TestTieredWriteEvictsPeerL1(t *testing.T) {
+	before := bus.subscribers()
+	shared := newL1[string](t, false)
+	podA := newTiered(t, newL1[string](t, true), shared)
+	defer podA.Close()
+	podBL1 := newL1[string](t, true)
+	podB := newTiered(t, podBL1, shared)
+	defer podB.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for bus.subscribers() < before+2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	flusher := podA.(backend.Flusher)
+	_ = podA.Set("key1", "v1")
+	_ = flusher.Flush(context.Background())
+	if value, err := podB.Get("key1"); err != nil || value != "v1" {
+		t.Fatalf("expected v1 from l2, got %q, %v", value, err)
+	}
+	if _, err := podBL1.Get("key1"); err != nil {
+		t.Fatal("expected pod b l1 to hold key1")
+	}
+
+	_ = podA.Set("key1", "v2")
+	if err := flusher.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if _, err := podBL1.Get("key1"); err == nil {
+		t.Error("expected pod b l1 copy to be invalidated")
+	}
+	if value, err := podB.Get("key1"); err != nil || value != "v2" {
+		t.Errorf("expected v2 after invalidation, got %q, %v", value, err)
+	}
+}
+
+func TestTieredClearNamespaceAndEpoch(t *testing.T) {
+	l1 := newL1[string](t, false)
+	l2 := newL1[string](t, false)
+	cache := newTiered(t, l1, l2)
+	defer cache.Close()
+
+	epoch := cache.(backend.EpochReporter).Epoch()
+	_ = cache.Set("user:1", "v")
+	_ = cache.Set("order:1", "v")
+	if err := cache.Clear(option.WithNamespace("user")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l1.Len() != 1 || l2.Len() != 1 {
+		t.Errorf("expected only order:1 to remain in both tiers, got %v and %v", l1.Keys(), l2.Keys())
+	}
+
+	if err := cache.Set("user:1", "stale", option.WithEpoch(epoch)); !errors.Is(err, constant.ErrStaleEpoch) {
+		t.Errorf("expected ErrStaleEpoch, got %v", err)
+	}
+	if _, err := l2.Get("user:1"); err == nil {
+		t.Error("expected a stale write not to reach l2")
+	}
+}
+
+func TestTieredUsesConfiguredDelivery(t *testing.T) {
+	before := bus.subscribers()
+	shared := newL1[string](t, false)
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{Tiered: &config.TieredConfig{L1Ttl: "1m"}},
+		Invalidation: &config.InvalidationConfig{
+			Type:     "tiered-test-bus",
+			Delivery: &config.DeliveryConfig{Set: "local"},
+		},
+	}
+	podA, err := NewTieredBackendWithCaches[string](newL1[string](t, true), shared, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer podA.Close()
+	podBL1 := newL1[string](t, true)
+	podB := newTiered(t, podBL1, shared)
+	defer podB.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for bus.subscribers() < before+2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	flusher := podA.(backend.Flusher)
+	_ = podA.Set("key1", "v1")
+	_, _ = podB.Get("key1")
+	_ = podA.Set("key1", "v2")
+	_ = flusher.Flush(context.Background())
+	if value, err := podBL1.Get("key1"); err != nil || value != "v1" {
+		t.Errorf("expected the configured local set to leave pod b l1 alone, got %q, %v", value, err)
+	}
+
+	_ = podA.Delete("key1")
+	_ = flusher.Flush(context.Background())
+	if _, err := podBL1.Get("key1"); err == nil {
+		t.Error("expected an unconfigured delete to fall back to async and evict pod b l1")
+	}
+}
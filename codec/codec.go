@@ -0,0 +1,153 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+type Codec[V any] interface {
+	Marshal(value V) ([]byte, error)
+	Unmarshal(data []byte) (V, error)
+}
+
+// Marshaler is the untyped form registered by codec drivers such as msgpack
+// or protobuf, which live in their own modules to keep the core dependency-free.
+type Marshaler interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	marshalersMu sync.RWMutex
+	marshalers   = map[string]Marshaler{
+		constant.JSONCodec: jsonMarshaler{},
+		constant.GobCodec:  gobMarshaler{},
+	}
+)
+
+// Register adds a codec under name. It is safe for concurrent use and fails
+// if name is already taken, including by the built-in json, gob and raw codecs.
+func Register(name string, marshaler Marshaler) error {
+	if name == constant.EmptyString || marshaler == nil {
+		return fmt.Errorf("codec registration needs a name and a marshaler")
+	}
+	marshalersMu.Lock()
+	defer marshalersMu.Unlock()
+	if _, exists := marshalers[name]; exists || name == constant.RawCodec {
+		return fmt.Errorf("codec %s is already registered", name)
+	}
+	marshalers[name] = marshaler
+	return nil
+}
+
+func New[V any](name string) (Codec[V], error) {
+	if name == constant.EmptyString {
+		name = constant.DefaultCodec
+	}
+	if name == constant.RawCodec {
+		return newRawCodec[V]()
+	}
+
+	marshalersMu.RLock()
+	marshaler, exists := marshalers[name]
+	marshalersMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown codec: %s", name)
+	}
+	return FromMarshaler[V](marshaler), nil
+}
+
+func FromMarshaler[V any](marshaler Marshaler) Codec[V] {
+	return marshalerCodec[V]{marshaler: marshaler}
+}
+
+func NewJSONCodec[V any]() Codec[V] {
+	return FromMarshaler[V](jsonMarshaler{})
+}
+
+func NewGobCodec[V any]() Codec[V] {
+	return FromMarshaler[V](gobMarshaler{})
+}
+
+func NewBytesCodec() Codec[[]byte] {
+	return bytesCodec{}
+}
+
+func NewStringCodec() Codec[string] {
+	return stringCodec{}
+}
+
+type marshalerCodec[V any] struct {
+	marshaler Marshaler
+}
+
+func (c marshalerCodec[V]) Marshal(value V) ([]byte, error) {
+	return c.marshaler.Marshal(&value)
+}
+
+func (c marshalerCodec[V]) Unmarshal(data []byte) (V, error) {
+	var value V
+	err := c.marshaler.Unmarshal(data, &value)
+	return value, err
+}
+
+type jsonMarshaler struct{}
+
+func (jsonMarshaler) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonMarshaler) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobMarshaler struct{}
+
+func (gobMarshaler) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobMarshaler) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type bytesCodec struct{}
+
+func (bytesCodec) Marshal(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (bytesCodec) Unmarshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type stringCodec struct{}
+
+func (stringCodec) Marshal(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+func (stringCodec) Unmarshal(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func newRawCodec[V any]() (Codec[V], error) {
+	var zero V
+	switch any(zero).(type) {
+	case []byte:
+		return any(bytesCodec{}).(Codec[V]), nil
+	case string:
+		return any(stringCodec{}).(Codec[V]), nil
+	default:
+		return nil, fmt.Errorf("raw codec only supports []byte and string values, got %T", zero)
+	}
+}
@@ -0,0 +1,229 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestGobCodecRoundTrip(t *testing.T) {
+	c := NewGobCodec[testUser]()
+
+	data, err := c.Marshal(testUser{Name: "john", Age: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Name != "john" || user.Age != 30 {
+		t.Errorf("unexpected value: %+v", user)
+	}
+}
+
+func TestGobCodecZeroValue(t *testing.T) {
+	c := NewGobCodec[int]()
+
+	data, err := c.Marshal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != 0 {
+		t.Errorf("expected 0, got %d", value)
+	}
+}
+
+func TestGobCodecInvalidData(t *testing.T) {
+	c := NewGobCodec[string]()
+
+	if _, err := c.Unmarshal([]byte("not gob")); err == nil {
+		t.Error("expected error for invalid data")
+	}
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	c := NewJSONCodec[testUser]()
+
+	data, err := c.Marshal(testUser{Name: "jane", Age: 25})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != `{"Name":"jane","Age":25}` {
+		t.Errorf("unexpected json: %s", data)
+	}
+
+	user, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Name != "jane" || user.Age != 25 {
+		t.Errorf("unexpected value: %+v", user)
+	}
+}
+
+func TestBytesCodec(t *testing.T) {
+	c := NewBytesCodec()
+
+	data, err := c.Marshal([]byte("payload"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(value) != "payload" {
+		t.Errorf("expected 'payload', got '%s'", value)
+	}
+}
+
+func TestStringCodec(t *testing.T) {
+	c := NewStringCodec()
+
+	data, err := c.Marshal("payload")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "payload" {
+		t.Errorf("expected 'payload', got '%s'", value)
+	}
+}
+
+func TestNewByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   string
+		wantErr bool
+	}{
+		{name: "default", codec: constant.EmptyString},
+		{name: "json", codec: constant.JSONCodec},
+		{name: "gob", codec: constant.GobCodec},
+		{name: "unknown", codec: "msgpack", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New[testUser](tt.codec)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := c.Marshal(testUser{Name: "john", Age: 30})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			user, err := c.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.Name != "john" {
+				t.Errorf("unexpected value: %+v", user)
+			}
+		})
+	}
+}
+
+func TestNewRawCodec(t *testing.T) {
+	if _, err := New[string](constant.RawCodec); err != nil {
+		t.Errorf("unexpected error for string: %v", err)
+	}
+	if _, err := New[[]byte](constant.RawCodec); err != nil {
+		t.Errorf("unexpected error for []byte: %v", err)
+	}
+	if _, err := New[int](constant.RawCodec); err == nil {
+		t.Error("expected error for unsupported raw type")
+	}
+}
+
+type upperMarshaler struct{}
+
+func (upperMarshaler) Marshal(v any) ([]byte, error) {
+	return []byte("X" + *v.(*string)), nil
+}
+
+func (upperMarshaler) Unmarshal(data []byte, v any) error {
+	*v.(*string) = string(data[1:])
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register("test-codec", upperMarshaler{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		marshalersMu.Lock()
+		delete(marshalers, "test-codec")
+		marshalersMu.Unlock()
+	}()
+
+	c, err := New[string]("test-codec")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := c.Marshal("value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "Xvalue" {
+		t.Errorf("expected registered codec to be used, got %s", data)
+	}
+
+	value, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value" {
+		t.Errorf("expected 'value', got '%s'", value)
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	for _, name := range []string{constant.JSONCodec, constant.GobCodec, constant.RawCodec, ""} {
+		if err := Register(name, upperMarshaler{}); err == nil {
+			t.Errorf("expected registering %q to fail", name)
+		}
+	}
+	if _, err := New[string](constant.JSONCodec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := NewJSONCodec[string]().Marshal("v"); string(data) != `"v"` {
+		t.Errorf("expected the built-in json codec to be kept, got %s", data)
+	}
+
+	if err := Register("dup-codec", upperMarshaler{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		marshalersMu.Lock()
+		delete(marshalers, "dup-codec")
+		marshalersMu.Unlock()
+	}()
+	if err := Register("dup-codec", upperMarshaler{}); err == nil {
+		t.Error("expected a second registration of the same name to fail")
+	}
+}
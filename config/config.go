@@ -1,36 +1,155 @@
 package config
 
 import (
-	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
 	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+	"github.com/halilbulentorhon/invacache-go/pkg/mempressure"
 )
 
 type InvaCacheConfig struct {
 	BackendName  string              `json:"backendName"`
 	Backend      *BackendConfig      `json:"backend"`
+	Codec        string              `json:"codec,omitempty"`
 	Invalidation *InvalidationConfig `json:"invalidation,omitempty"`
+	LogLevel     string              `json:"logLevel,omitempty"`
+	Logger       logger.Logger       `json:"-"`
+	LogHandler   slog.Handler        `json:"-"`
+	Clock        clock.Clock         `json:"-"`
 }
 
 type BackendConfig struct {
 	InMemory *InMemoryConfig `json:"inMemory"`
+	Remote   *RemoteConfig   `json:"remote,omitempty"`
+	Tiered   *TieredConfig   `json:"tiered,omitempty"`
+	Disk     *DiskConfig     `json:"disk,omitempty"`
+	OffHeap  *OffHeapConfig  `json:"offHeap,omitempty"`
 }
 
 type InMemoryConfig struct {
-	ShardCount      int           `json:"shardCount"`
+	ShardCount      int                   `json:"shardCount"`
+	SweeperInterval time.Duration         `json:"sweeperInterval"`
+	Capacity        int                   `json:"capacity"`
+	Ttl             string                `json:"ttl"`
+	MemoryPressure  *MemoryPressureConfig `json:"memoryPressure,omitempty"`
+	SnapshotPath    string                `json:"snapshotPath,omitempty"`
+	DefaultTTL      time.Duration         `json:"-"`
+}
+
+type MemoryPressureConfig struct {
+	OnShed              func(mempressure.Event) `json:"-"`
+	SoftLimit           uint64                  `json:"softLimit"`
+	Interval            time.Duration           `json:"interval"`
+	Threshold           float64                 `json:"threshold"`
+	RecoverThreshold    float64                 `json:"recoverThreshold"`
+	ShedFraction        float64                 `json:"shedFraction"`
+	MinCapacityFraction float64                 `json:"minCapacityFraction"`
+}
+
+type RemoteConfig struct {
+	DriverConfig     map[string]any `json:"driverConfig,omitempty"`
+	Ttl              string         `json:"ttl"`
+	LockTTL          time.Duration  `json:"lockTtl"`
+	LockWait         time.Duration  `json:"lockWait"`
+	OperationTimeout time.Duration  `json:"operationTimeout"`
+	DefaultTTL       time.Duration  `json:"-"`
+}
+
+type TieredConfig struct {
+	L2Backend string        `json:"l2Backend"`
+	L1Ttl     string        `json:"l1Ttl"`
+	L1TTL     time.Duration `json:"-"`
+}
+
+type DiskConfig struct {
+	Dir             string        `json:"dir"`
+	Ttl             string        `json:"ttl"`
+	MaxBytes        int64         `json:"maxBytes"`
 	SweeperInterval time.Duration `json:"sweeperInterval"`
-	Capacity        int           `json:"capacity"`
+	DefaultTTL      time.Duration `json:"-"`
+}
+
+type OffHeapConfig struct {
 	Ttl             string        `json:"ttl"`
+	MaxBytes        int64         `json:"maxBytes"`
+	ShardCount      int           `json:"shardCount"`
+	SweeperInterval time.Duration `json:"sweeperInterval"`
 	DefaultTTL      time.Duration `json:"-"`
 }
 
 type InvalidationConfig struct {
-	Type         string         `json:"type"`
-	DriverConfig map[string]any `json:"driverConfig,omitempty"`
+	Type         string             `json:"type"`
+	DriverConfig map[string]any     `json:"driverConfig,omitempty"`
+	Resubscribe  *ResubscribeConfig `json:"resubscribe,omitempty"`
+	Publish      *PublishConfig     `json:"publish,omitempty"`
+	Delivery     *DeliveryConfig    `json:"delivery,omitempty"`
+	Dispatch     *DispatchConfig    `json:"dispatch,omitempty"`
+	Envelope     *EnvelopeConfig    `json:"envelope,omitempty"`
+	// PubSub, when set, is used as is instead of building the driver named by
+	// Type. The cache closes it on Close.
+	PubSub invalidation.PubSub `json:"-"`
+}
+
+// ResubscribeConfig controls how a dropped invalidation subscription is
+// restarted. Zero fields use the defaults; a negative Jitter disables jitter.
+// ClearOnGap clears the local cache before each resubscription, since
+// invalidations sent while disconnected are lost.
+type ResubscribeConfig struct {
+	InitialBackoff time.Duration `json:"initialBackoff"`
+	MaxBackoff     time.Duration `json:"maxBackoff"`
+	Multiplier     float64       `json:"multiplier"`
+	Jitter         float64       `json:"jitter"`
+	ClearOnGap     bool          `json:"clearOnGap"`
+}
+
+// PublishConfig controls the queue invalidations wait in before a background
+// worker publishes them. Overflow is one of "block", "drop-oldest" or "fail";
+// a negative MaxRetries disables retries. A positive BatchWindow publishes up
+// to BatchSize keys collected within the window as one message.
+type PublishConfig struct {
+	Overflow        string        `json:"overflow"`
+	QueueSize       int           `json:"queueSize"`
+	MaxRetries      int           `json:"maxRetries"`
+	RetryBackoff    time.Duration `json:"retryBackoff"`
+	MaxRetryBackoff time.Duration `json:"maxRetryBackoff"`
+	BatchSize       int           `json:"batchSize"`
+	BatchWindow     time.Duration `json:"batchWindow"`
+}
+
+// DeliveryConfig sets the cache-level delivery for calls that do not choose
+// one: "local", "async" or "sync". Unset operations stay local.
+type DeliveryConfig struct {
+	Set    string `json:"set"`
+	Delete string `json:"delete"`
+	Clear  string `json:"clear"`
+}
+
+// DispatchConfig, when set, runs received invalidations on Workers goroutines
+// partitioned by key, so a slow handler or a large Clear does not stall the
+// subscription. QueueSize bounds the messages waiting across all workers.
+type DispatchConfig struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queueSize"`
+}
+
+// EnvelopeConfig, when set, sends every invalidation with a message ID, the
+// sender's ID and a hybrid logical clock stamp. Receivers drop messages seen
+// within DedupeWindow, their own messages, and invalidations older than the
+// local entry's last write. Enable it only once every node can read
+// envelopes.
+type EnvelopeConfig struct {
+	DedupeWindow time.Duration `json:"dedupeWindow"`
 }
 
 func (cfg *InvaCacheConfig) ApplyDefaults() {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
 	if cfg.Backend == nil {
 		cfg.Backend = &BackendConfig{}
 	}
@@ -46,14 +165,241 @@ func (cfg *InvaCacheConfig) ApplyDefaults() {
 	if cfg.Backend.InMemory.SweeperInterval <= 0 {
 		cfg.Backend.InMemory.SweeperInterval = constant.DefaultSweeperInterval
 	}
-	if cfg.Backend.InMemory.Capacity <= cfg.Backend.InMemory.ShardCount {
-		panic(fmt.Sprintf("shard count(%d) cannot be greater then or equal to capacity(%d)", cfg.Backend.InMemory.ShardCount, cfg.Backend.InMemory.Capacity))
+	applyTTL(cfg.Backend.InMemory.Ttl, &cfg.Backend.InMemory.DefaultTTL)
+	if cfg.Backend.InMemory.MemoryPressure != nil {
+		cfg.Backend.InMemory.MemoryPressure.applyDefaults()
 	}
-	if cfg.Backend.InMemory.Ttl != "" {
-		duration, err := time.ParseDuration(cfg.Backend.InMemory.Ttl)
-		if err != nil {
-			panic(fmt.Sprintf("invalid ttl format '%s': %v", cfg.Backend.InMemory.Ttl, err))
+	if cfg.Backend.Remote != nil {
+		cfg.Backend.Remote.applyDefaults()
+	}
+	if cfg.Backend.Tiered != nil {
+		cfg.Backend.Tiered.applyDefaults()
+	}
+	if cfg.Backend.Disk != nil {
+		cfg.Backend.Disk.applyDefaults()
+	}
+	if cfg.Backend.OffHeap != nil {
+		cfg.Backend.OffHeap.applyDefaults()
+	}
+	if cfg.Invalidation != nil {
+		// Defaults go into copies, so a config shared by several caches is
+		// never changed under them.
+		inv := *cfg.Invalidation
+		cfg.Invalidation = &inv
+		inv.Resubscribe = cloneOrNew(inv.Resubscribe)
+		inv.Resubscribe.applyDefaults()
+		inv.Publish = cloneOrNew(inv.Publish)
+		inv.Publish.applyDefaults()
+		if inv.Dispatch != nil {
+			inv.Dispatch = cloneOrNew(inv.Dispatch)
+			inv.Dispatch.applyDefaults()
 		}
-		cfg.Backend.InMemory.DefaultTTL = duration
+		if inv.Envelope != nil && inv.Envelope.DedupeWindow <= 0 {
+			inv.Envelope = &EnvelopeConfig{DedupeWindow: constant.DefaultDedupeWindow}
+		}
+	}
+}
+
+// cloneOrNew returns a copy of *p, or a zero value when p is nil.
+func cloneOrNew[T any](p *T) *T {
+	if p == nil {
+		return new(T)
+	}
+	clone := *p
+	return &clone
+}
+
+func applyTTL(ttl string, target *time.Duration) {
+	if ttl == constant.EmptyString {
+		return
+	}
+	if duration, err := time.ParseDuration(ttl); err == nil {
+		*target = duration
+	}
+}
+
+func (cfg *ResubscribeConfig) applyDefaults() {
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = constant.DefaultResubscribeInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = max(constant.DefaultResubscribeMaxBackoff, cfg.InitialBackoff)
+	}
+	if cfg.Multiplier == 0 {
+		cfg.Multiplier = constant.DefaultResubscribeMultiplier
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = constant.DefaultResubscribeJitter
+	}
+}
+
+func (cfg *PublishConfig) applyDefaults() {
+	if cfg.Overflow == constant.EmptyString {
+		cfg.Overflow = constant.DefaultPublishOverflow
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = constant.DefaultPublishQueueSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = constant.DefaultPublishMaxRetries
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = constant.DefaultPublishRetryBackoff
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = max(constant.DefaultPublishMaxRetryBackoff, cfg.RetryBackoff)
+	}
+	if cfg.BatchWindow > 0 && cfg.BatchSize <= 0 {
+		cfg.BatchSize = constant.DefaultPublishBatchSize
+	}
+}
+
+func (cfg *PublishConfig) queueConfig() invalidation.QueueConfig {
+	return invalidation.QueueConfig{
+		Overflow:    invalidation.OverflowPolicy(cfg.Overflow),
+		Size:        cfg.QueueSize,
+		MaxRetries:  max(cfg.MaxRetries, 0),
+		BatchSize:   cfg.BatchSize,
+		BatchWindow: cfg.BatchWindow,
+		Backoff: invalidation.Backoff{
+			Initial:    cfg.RetryBackoff,
+			Max:        cfg.MaxRetryBackoff,
+			Multiplier: constant.DefaultResubscribeMultiplier,
+			Jitter:     constant.DefaultResubscribeJitter,
+		},
+	}
+}
+
+func (cfg *DispatchConfig) applyDefaults() {
+	if cfg.Workers <= 0 {
+		cfg.Workers = constant.DefaultDispatchWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = constant.DefaultDispatchQueueSize
+	}
+}
+
+// DeliveryDefaults resolves the configured deliveries. Without a delivery
+// config every operation is local unless the call asks otherwise.
+func (cfg *InvalidationConfig) DeliveryDefaults() option.DeliveryDefaults {
+	return cfg.DeliveryDefaultsOr(option.DeliveryLocal)
+}
+
+// DeliveryDefaultsOr resolves the configured deliveries, using fallback for
+// the operations the config leaves unset.
+func (cfg *InvalidationConfig) DeliveryDefaultsOr(fallback option.Delivery) option.DeliveryDefaults {
+	if cfg == nil || cfg.Delivery == nil {
+		return option.DeliveryDefaults{Set: fallback, Delete: fallback, Clear: fallback}
+	}
+	return option.DeliveryDefaults{
+		Set:    deliveryOr(cfg.Delivery.Set, fallback),
+		Delete: deliveryOr(cfg.Delivery.Delete, fallback),
+		Clear:  deliveryOr(cfg.Delivery.Clear, fallback),
+	}
+}
+
+func deliveryOr(value string, fallback option.Delivery) option.Delivery {
+	delivery, _ := option.ParseDelivery(value)
+	return delivery.Or(fallback)
+}
+
+// CoordinatorOptions translates the resubscribe and publish settings into
+// coordinator options.
+func (cfg *InvalidationConfig) CoordinatorOptions(onGap func()) []invalidation.CoordinatorOption {
+	var opts []invalidation.CoordinatorOption
+	if cfg.Resubscribe != nil {
+		opts = append(opts, cfg.Resubscribe.CoordinatorOptions(onGap)...)
+	}
+	if cfg.Publish != nil {
+		opts = append(opts, invalidation.WithPublishQueue(cfg.Publish.queueConfig()))
+	}
+	if cfg.Dispatch != nil {
+		opts = append(opts, invalidation.WithDispatch(invalidation.DispatchConfig{
+			Workers:   positiveOr(cfg.Dispatch.Workers, constant.DefaultDispatchWorkers),
+			QueueSize: positiveOr(cfg.Dispatch.QueueSize, constant.DefaultDispatchQueueSize),
+		}))
+	}
+	if cfg.Envelope != nil {
+		opts = append(opts, invalidation.WithEnvelope(cfg.Envelope.DedupeWindow))
+	}
+	return opts
+}
+
+// CoordinatorOptions translates the config into coordinator options; onGap is
+// installed only when ClearOnGap is set.
+func (cfg *ResubscribeConfig) CoordinatorOptions(onGap func()) []invalidation.CoordinatorOption {
+	opts := []invalidation.CoordinatorOption{invalidation.WithBackoff(invalidation.Backoff{
+		Initial:    cfg.InitialBackoff,
+		Max:        cfg.MaxBackoff,
+		Multiplier: cfg.Multiplier,
+		Jitter:     max(cfg.Jitter, 0),
+	})}
+	if cfg.ClearOnGap {
+		opts = append(opts, invalidation.WithGapHandler(onGap))
+	}
+	return opts
+}
+
+func (cfg *MemoryPressureConfig) applyDefaults() {
+	if cfg.Interval <= 0 {
+		cfg.Interval = constant.DefaultPressureInterval
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = constant.DefaultPressureThreshold
+	}
+	if cfg.RecoverThreshold <= 0 {
+		cfg.RecoverThreshold = min(constant.DefaultPressureRecoverThreshold, cfg.Threshold)
+	}
+	if cfg.ShedFraction <= 0 {
+		cfg.ShedFraction = constant.DefaultShedFraction
+	}
+	if cfg.MinCapacityFraction <= 0 {
+		cfg.MinCapacityFraction = constant.DefaultMinCapacityFraction
+	}
+}
+
+func (cfg *OffHeapConfig) applyDefaults() {
+	if cfg.ShardCount <= 0 {
+		cfg.ShardCount = constant.DefaultShardCount
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = constant.DefaultOffHeapMaxBytes
+	}
+	if cfg.SweeperInterval <= 0 {
+		cfg.SweeperInterval = constant.DefaultSweeperInterval
+	}
+	applyTTL(cfg.Ttl, &cfg.DefaultTTL)
+}
+
+func (cfg *DiskConfig) applyDefaults() {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = constant.DefaultDiskMaxBytes
+	}
+	if cfg.SweeperInterval <= 0 {
+		cfg.SweeperInterval = constant.DefaultSweeperInterval
+	}
+	applyTTL(cfg.Ttl, &cfg.DefaultTTL)
+}
+
+func (cfg *TieredConfig) applyDefaults() {
+	if cfg.L2Backend == "" {
+		cfg.L2Backend = constant.RedisBackend
+	}
+	applyTTL(cfg.L1Ttl, &cfg.L1TTL)
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = constant.DefaultL1TTL
+	}
+}
+
+func (cfg *RemoteConfig) applyDefaults() {
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = constant.DefaultLockTTL
+	}
+	if cfg.LockWait <= 0 {
+		cfg.LockWait = constant.DefaultLockWait
+	}
+	if cfg.OperationTimeout <= 0 {
+		cfg.OperationTimeout = constant.DefaultOperationTimeout
 	}
+	applyTTL(cfg.Ttl, &cfg.DefaultTTL)
 }
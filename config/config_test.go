@@ -79,13 +79,10 @@ func TestApplyDefaultsWithInvalidTTL(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("expected panic for invalid TTL format")
-		}
-	}()
-
 	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error for invalid TTL format")
+	}
 }
 
 func TestApplyDefaultsWithEmptyTTL(t *testing.T) {
@@ -115,13 +112,10 @@ func TestApplyDefaultsCapacityLessThanShardCount(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("expected panic when capacity equals shard count")
-		}
-	}()
-
 	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error when capacity equals shard count")
+	}
 }
 
 func TestApplyDefaultsCapacityLessThanShardCountNegative(t *testing.T) {
@@ -134,13 +128,10 @@ func TestApplyDefaultsCapacityLessThanShardCountNegative(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("expected panic when capacity is less than shard count")
-		}
-	}()
-
 	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error when capacity is less than shard count")
+	}
 }
 
 func TestApplyDefaultsVariousTTLFormats(t *testing.T) {
@@ -174,3 +165,180 @@ func TestApplyDefaultsVariousTTLFormats(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyDefaultsRemote(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Backend: &BackendConfig{
+			Remote: &RemoteConfig{Ttl: "5m"},
+		},
+	}
+	cfg.ApplyDefaults()
+
+	remote := cfg.Backend.Remote
+	if remote.DefaultTTL != 5*time.Minute {
+		t.Errorf("expected default TTL 5m, got %v", remote.DefaultTTL)
+	}
+	if remote.LockTTL != constant.DefaultLockTTL {
+		t.Errorf("expected lock TTL %v, got %v", constant.DefaultLockTTL, remote.LockTTL)
+	}
+	if remote.LockWait != constant.DefaultLockWait {
+		t.Errorf("expected lock wait %v, got %v", constant.DefaultLockWait, remote.LockWait)
+	}
+	if remote.OperationTimeout != constant.DefaultOperationTimeout {
+		t.Errorf("expected operation timeout %v, got %v", constant.DefaultOperationTimeout, remote.OperationTimeout)
+	}
+}
+
+func TestApplyDefaultsTiered(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Backend: &BackendConfig{
+			Tiered: &TieredConfig{},
+		},
+	}
+	cfg.ApplyDefaults()
+
+	if cfg.Backend.Tiered.L2Backend != constant.RedisBackend {
+		t.Errorf("expected l2 backend %s, got %s", constant.RedisBackend, cfg.Backend.Tiered.L2Backend)
+	}
+	if cfg.Backend.Tiered.L1TTL != constant.DefaultL1TTL {
+		t.Errorf("expected l1 TTL %v, got %v", constant.DefaultL1TTL, cfg.Backend.Tiered.L1TTL)
+	}
+
+	cfg.Backend.Tiered = &TieredConfig{L1Ttl: "15s"}
+	cfg.ApplyDefaults()
+	if cfg.Backend.Tiered.L1TTL != 15*time.Second {
+		t.Errorf("expected l1 TTL 15s, got %v", cfg.Backend.Tiered.L1TTL)
+	}
+}
+
+func TestApplyDefaultsMemoryPressure(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Backend: &BackendConfig{
+			InMemory: &InMemoryConfig{MemoryPressure: &MemoryPressureConfig{}},
+		},
+	}
+	cfg.ApplyDefaults()
+
+	pressure := cfg.Backend.InMemory.MemoryPressure
+	if pressure.Interval != constant.DefaultPressureInterval {
+		t.Errorf("expected interval %v, got %v", constant.DefaultPressureInterval, pressure.Interval)
+	}
+	if pressure.Threshold != constant.DefaultPressureThreshold || pressure.RecoverThreshold != constant.DefaultPressureRecoverThreshold {
+		t.Errorf("unexpected thresholds %v and %v", pressure.Threshold, pressure.RecoverThreshold)
+	}
+	if pressure.ShedFraction != constant.DefaultShedFraction || pressure.MinCapacityFraction != constant.DefaultMinCapacityFraction {
+		t.Errorf("unexpected fractions %v and %v", pressure.ShedFraction, pressure.MinCapacityFraction)
+	}
+}
+
+func TestApplyDefaultsMemoryPressureInvalidThresholds(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Backend: &BackendConfig{
+			InMemory: &InMemoryConfig{
+				MemoryPressure: &MemoryPressureConfig{Threshold: 0.5, RecoverThreshold: 0.8},
+			},
+		},
+	}
+	cfg.ApplyDefaults()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected validation error when recover threshold exceeds threshold")
+	}
+}
+
+func TestApplyDefaultsResubscribe(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{Type: "redis"}}
+	cfg.ApplyDefaults()
+
+	resubscribe := cfg.Invalidation.Resubscribe
+	if resubscribe == nil {
+		t.Fatal("expected resubscribe defaults")
+	}
+	if resubscribe.InitialBackoff != constant.DefaultResubscribeInitialBackoff || resubscribe.MaxBackoff != constant.DefaultResubscribeMaxBackoff {
+		t.Errorf("unexpected backoff bounds %v and %v", resubscribe.InitialBackoff, resubscribe.MaxBackoff)
+	}
+	if resubscribe.Multiplier != constant.DefaultResubscribeMultiplier || resubscribe.Jitter != constant.DefaultResubscribeJitter {
+		t.Errorf("unexpected multiplier %v and jitter %v", resubscribe.Multiplier, resubscribe.Jitter)
+	}
+	if resubscribe.ClearOnGap {
+		t.Error("expected clear on gap to be off by default")
+	}
+}
+
+func TestApplyDefaultsKeepsNegativeJitter(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{Type: "redis", Resubscribe: &ResubscribeConfig{Jitter: -1}}}
+	cfg.ApplyDefaults()
+
+	if jitter := cfg.Invalidation.Resubscribe.Jitter; jitter != -1 {
+		t.Errorf("expected a negative jitter to disable jitter, got %v", jitter)
+	}
+	if errs := cfg.Invalidation.Resubscribe.validate(); len(errs) != 0 {
+		t.Errorf("expected a negative jitter to be valid, got %v", errs)
+	}
+	if errs := (&ResubscribeConfig{Multiplier: -2}).validate(); len(errs) != 1 {
+		t.Errorf("expected a negative multiplier to be rejected, got %v", errs)
+	}
+}
+
+func TestApplyDefaultsCopiesInvalidationConfig(t *testing.T) {
+	shared := &InvalidationConfig{
+		Type:        "redis",
+		Resubscribe: &ResubscribeConfig{ClearOnGap: true},
+		Dispatch:    &DispatchConfig{},
+		Envelope:    &EnvelopeConfig{},
+	}
+	cfg := InvaCacheConfig{Invalidation: shared}
+	cfg.ApplyDefaults()
+
+	if cfg.Invalidation == shared || !cfg.Invalidation.Resubscribe.ClearOnGap || cfg.Invalidation.Resubscribe.Jitter == 0 {
+		t.Errorf("expected defaults in a copy of the config, got %+v", cfg.Invalidation)
+	}
+	if shared.Publish != nil || shared.Resubscribe.Jitter != 0 || shared.Dispatch.Workers != 0 || shared.Envelope.DedupeWindow != 0 {
+		t.Errorf("expected the caller's config to be left unchanged, got %+v", shared)
+	}
+}
+
+func TestApplyDefaultsPublish(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{Type: "redis", Publish: &PublishConfig{MaxRetries: -1}}}
+	cfg.ApplyDefaults()
+
+	publish := cfg.Invalidation.Publish
+	if publish.Overflow != constant.DefaultPublishOverflow || publish.QueueSize != constant.DefaultPublishQueueSize {
+		t.Errorf("unexpected overflow %q and queue size %d", publish.Overflow, publish.QueueSize)
+	}
+	if publish.RetryBackoff != constant.DefaultPublishRetryBackoff || publish.MaxRetryBackoff != constant.DefaultPublishMaxRetryBackoff {
+		t.Errorf("unexpected retry backoff %v and %v", publish.RetryBackoff, publish.MaxRetryBackoff)
+	}
+	if publish.MaxRetries != -1 {
+		t.Errorf("expected negative max retries to be kept, got %d", publish.MaxRetries)
+	}
+	if queue := publish.queueConfig(); queue.MaxRetries != 0 {
+		t.Errorf("expected retries to be disabled, got %d", queue.MaxRetries)
+	}
+}
+
+func TestApplyDefaultsDispatch(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{Type: "redis"}}
+	cfg.ApplyDefaults()
+	if cfg.Invalidation.Dispatch != nil {
+		t.Error("expected dispatch to stay disabled unless configured")
+	}
+
+	cfg.Invalidation.Dispatch = &DispatchConfig{Workers: 8}
+	cfg.ApplyDefaults()
+	if dispatch := cfg.Invalidation.Dispatch; dispatch.Workers != 8 || dispatch.QueueSize != constant.DefaultDispatchQueueSize {
+		t.Errorf("unexpected dispatch config %+v", dispatch)
+	}
+}
+
+func TestApplyDefaultsEnvelope(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{Type: "redis", Envelope: &EnvelopeConfig{}}}
+	cfg.ApplyDefaults()
+	if window := cfg.Invalidation.Envelope.DedupeWindow; window != constant.DefaultDedupeWindow {
+		t.Errorf("expected default dedupe window, got %v", window)
+	}
+
+	cfg.Invalidation.Envelope.DedupeWindow = -time.Second
+	if errs := cfg.Invalidation.validate(); len(errs) == 0 {
+		t.Error("expected a negative dedupe window to be rejected")
+	}
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/decode"
+)
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Load builds a config from, in increasing order of precedence, the file at
+// path, environment variables under envPrefix and the overrides, which run
+// last. An empty path or prefix skips that source. ${ENV} references in
+// driver configs are expanded before the overrides run, and the result is
+// validated.
+func Load(path, envPrefix string, overrides ...func(*InvaCacheConfig)) (InvaCacheConfig, error) {
+	var cfg InvaCacheConfig
+
+	if path != "" {
+		if err := loadFileInto(path, &cfg); err != nil {
+			return InvaCacheConfig{}, err
+		}
+	}
+	if envPrefix != "" {
+		if err := loadEnvInto(envPrefix, os.Environ(), &cfg); err != nil {
+			return InvaCacheConfig{}, err
+		}
+	}
+	if err := cfg.expandDriverConfigs(); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	for _, override := range overrides {
+		override(&cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	return cfg, nil
+}
+
+// LoadFile reads a JSON or YAML config file, chosen by extension (.json,
+// .yaml, .yml), and expands ${ENV} references in driver configs.
+func LoadFile(path string) (InvaCacheConfig, error) {
+	var cfg InvaCacheConfig
+	if err := loadFileInto(path, &cfg); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	if err := cfg.expandDriverConfigs(); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	return cfg, nil
+}
+
+// FromEnv reads a config from environment variables named after the json
+// path of each field, upper-cased and joined with underscores under prefix,
+// e.g. INVACACHE_BACKEND_INMEMORY_CAPACITY=5000. Keys below a driverConfig
+// are taken verbatim: INVACACHE_INVALIDATION_DRIVERCONFIG_Address.
+func FromEnv(prefix string) (InvaCacheConfig, error) {
+	var cfg InvaCacheConfig
+	if err := loadEnvInto(prefix, os.Environ(), &cfg); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	if err := cfg.expandDriverConfigs(); err != nil {
+		return InvaCacheConfig{}, err
+	}
+	return cfg, nil
+}
+
+// UnmarshalJSON accepts durations either as nanoseconds or as strings such as
+// "30s" for every duration field.
+func (cfg *InvaCacheConfig) UnmarshalJSON(data []byte) error {
+	raw, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return decode.Into(raw, cfg)
+}
+
+func loadFileInto(path string, cfg *InvaCacheConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		raw, err = decodeJSON(data)
+	case ".yaml", ".yml":
+		raw, err = parseYAML(data)
+	default:
+		return fmt.Errorf("unsupported config file extension %q, expected .json, .yaml or .yml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := decode.Into(raw, cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func decodeJSON(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func loadEnvInto(prefix string, environ []string, cfg *InvaCacheConfig) error {
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+	tree := make(map[string]any)
+
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(name, prefix), "_")
+		if err := insertEnv(tree, reflect.TypeOf(InvaCacheConfig{}), segments, value); err != nil {
+			return fmt.Errorf("invalid environment variable %s: %w", name, err)
+		}
+	}
+
+	if err := decode.Into(tree, cfg); err != nil {
+		return fmt.Errorf("invalid environment config: %w", err)
+	}
+	return nil
+}
+
+func insertEnv(tree map[string]any, t reflect.Type, segments []string, value string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Values stay strings; the driver's typed decode converts them, so a
+	// password such as "007" is not turned into a number.
+	if t.Kind() == reflect.Map {
+		tree[strings.Join(segments, "_")] = value
+		return nil
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("unexpected nested key %s", strings.Join(segments, "_"))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := decode.FieldName(t.Field(i))
+		if !ok || !strings.EqualFold(name, segments[0]) {
+			continue
+		}
+
+		fieldType := t.Field(i).Type
+		if len(segments) == 1 {
+			if kind := indirect(fieldType).Kind(); kind == reflect.Struct || kind == reflect.Map {
+				return fmt.Errorf("%s needs a nested key", name)
+			}
+			tree[name] = value
+			return nil
+		}
+
+		sub, ok := tree[name].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			tree[name] = sub
+		}
+		return insertEnv(sub, fieldType, segments[1:], value)
+	}
+
+	return fmt.Errorf("unknown config key %s", segments[0])
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func (cfg *InvaCacheConfig) expandDriverConfigs() error {
+	if cfg.Invalidation != nil {
+		if err := expandEnv(cfg.Invalidation.DriverConfig); err != nil {
+			return fmt.Errorf("invalidation driver config: %w", err)
+		}
+	}
+	if cfg.Backend != nil && cfg.Backend.Remote != nil {
+		if err := expandEnv(cfg.Backend.Remote.DriverConfig); err != nil {
+			return fmt.Errorf("remote driver config: %w", err)
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces ${NAME} and ${NAME:-default} references in every string
+// of m, recursively. Referencing an unset variable without a default is an
+// error so a missing secret does not silently become an empty password.
+func expandEnv(m map[string]any) error {
+	for key, value := range m {
+		switch val := value.(type) {
+		case string:
+			expanded, err := expandString(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			m[key] = expanded
+		case map[string]any:
+			if err := expandEnv(val); err != nil {
+				return fmt.Errorf("%s.%w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func expandString(s string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		match := envReference.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(match[1]); ok {
+			return value
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		missing = append(missing, match[1])
+		return ref
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
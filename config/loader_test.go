@@ -0,0 +1,233 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/decode"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestUnmarshalJSONHumanDurations(t *testing.T) {
+	data := `{
+		"backendName": "in-memory",
+		"backend": {
+			"inMemory": {"capacity": 500, "shardCount": 4, "sweeperInterval": "30s", "ttl": "5m"},
+			"remote": {"lockTtl": 2000000000, "lockWait": "1.5s"}
+		}
+	}`
+
+	var cfg InvaCacheConfig
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Backend.InMemory.SweeperInterval != 30*time.Second || cfg.Backend.InMemory.Capacity != 500 {
+		t.Errorf("unexpected in-memory config: %+v", cfg.Backend.InMemory)
+	}
+	if cfg.Backend.Remote.LockTTL != 2*time.Second || cfg.Backend.Remote.LockWait != 1500*time.Millisecond {
+		t.Errorf("unexpected remote config: %+v", cfg.Backend.Remote)
+	}
+
+	if err := json.Unmarshal([]byte(`{"backend": {"inMemory": {"sweeperInterval": "often"}}}`), &cfg); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	t.Setenv("TEST_REDIS_PASSWORD", "s3cret")
+
+	path := writeConfigFile(t, "cache.json", `{
+		"backendName": "in-memory",
+		"backend": {"inMemory": {"capacity": 200, "sweeperInterval": "1m"}},
+		"invalidation": {
+			"type": "validate-test",
+			"driverConfig": {"Address": "localhost:6379", "Password": "${TEST_REDIS_PASSWORD}", "DB": 3}
+		}
+	}`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Backend.InMemory.Capacity != 200 || cfg.Backend.InMemory.SweeperInterval != time.Minute {
+		t.Errorf("unexpected in-memory config: %+v", cfg.Backend.InMemory)
+	}
+	driver := cfg.Invalidation.DriverConfig
+	if driver["Password"] != "s3cret" {
+		t.Errorf("expected password to be expanded, got %v", driver["Password"])
+	}
+	if driver["DB"] != 3 {
+		t.Errorf("expected integral number to decode as int, got %#v", driver["DB"])
+	}
+}
+
+func TestLoadFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "cache.yaml", `
+backendName: in-memory
+backend:
+  inMemory:
+    capacity: 300
+    shardCount: 4
+    sweeperInterval: 45s
+    memoryPressure:
+      softLimit: 1048576
+      interval: 500ms
+      threshold: 0.8
+invalidation:
+  type: validate-test
+  driverConfig:
+    Address: ${TEST_REDIS_ADDRESS:-localhost:6379}
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inMemory := cfg.Backend.InMemory
+	if inMemory.Capacity != 300 || inMemory.ShardCount != 4 || inMemory.SweeperInterval != 45*time.Second {
+		t.Errorf("unexpected in-memory config: %+v", inMemory)
+	}
+	pressure := inMemory.MemoryPressure
+	if pressure.SoftLimit != 1<<20 || pressure.Interval != 500*time.Millisecond || pressure.Threshold != 0.8 {
+		t.Errorf("unexpected memory pressure config: %+v", pressure)
+	}
+	if cfg.Invalidation.DriverConfig["Address"] != "localhost:6379" {
+		t.Errorf("expected default to be used, got %v", cfg.Invalidation.DriverConfig["Address"])
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	if _, err := LoadFile(writeConfigFile(t, "cache.toml", "")); err == nil || !strings.Contains(err.Error(), "unsupported config file extension") {
+		t.Errorf("expected unsupported extension error, got %v", err)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if _, err := LoadFile(writeConfigFile(t, "cache.yml", "backend:\n  inMemory:\n    capacity: lots\n")); err == nil || !strings.Contains(err.Error(), "backend.inMemory.capacity") {
+		t.Errorf("expected field path in error, got %v", err)
+	}
+
+	path := writeConfigFile(t, "cache.json", `{"invalidation": {"type": "x", "driverConfig": {"Password": "${TEST_UNSET_PASSWORD}"}}}`)
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "TEST_UNSET_PASSWORD is not set") {
+		t.Errorf("expected unset variable error, got %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TESTCACHE_BACKENDNAME", "in-memory")
+	t.Setenv("TESTCACHE_BACKEND_INMEMORY_CAPACITY", "5000")
+	t.Setenv("TESTCACHE_BACKEND_INMEMORY_SWEEPERINTERVAL", "2m")
+	t.Setenv("TESTCACHE_BACKEND_REMOTE_DRIVERCONFIG_key_prefix", "app:")
+	t.Setenv("TESTCACHE_INVALIDATION_TYPE", "validate-test")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_Address", "redis:6379")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_DB", "4")
+	t.Setenv("TEST_REDIS_PASSWORD", "from-env")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_Password", "${TEST_REDIS_PASSWORD}")
+
+	cfg, err := FromEnv("TESTCACHE")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.BackendName != constant.InMemoryBackend || cfg.Backend.InMemory.Capacity != 5000 || cfg.Backend.InMemory.SweeperInterval != 2*time.Minute {
+		t.Errorf("unexpected config: %+v %+v", cfg, cfg.Backend.InMemory)
+	}
+	if cfg.Backend.Remote.DriverConfig["key_prefix"] != "app:" {
+		t.Errorf("expected driver config key to be kept verbatim, got %v", cfg.Backend.Remote.DriverConfig)
+	}
+	driver := cfg.Invalidation.DriverConfig
+	if driver["Address"] != "redis:6379" || driver["DB"] != "4" || driver["Password"] != "from-env" {
+		t.Errorf("unexpected driver config: %#v", driver)
+	}
+}
+
+func TestFromEnvKeepsDriverValuesAsStrings(t *testing.T) {
+	t.Setenv("TESTCACHE_INVALIDATION_TYPE", "validate-test")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_password", "007")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_channel", "1e3")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_db", "4")
+	t.Setenv("TESTCACHE_INVALIDATION_DRIVERCONFIG_tls", "true")
+
+	cfg, err := FromEnv("TESTCACHE")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type driverConfig struct {
+		Password string `json:"password"`
+		Channel  string `json:"channel"`
+		DB       int    `json:"db"`
+		TLS      bool   `json:"tls"`
+	}
+	driver, err := decode.Config[driverConfig](cfg.Invalidation.DriverConfig)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if driver != (driverConfig{Password: "007", Channel: "1e3", DB: 4, TLS: true}) {
+		t.Errorf("unexpected driver config %+v", driver)
+	}
+}
+
+func TestFromEnvErrors(t *testing.T) {
+	t.Setenv("BADCACHE_BACKEND_INMEMORY_CAPACTY", "5")
+	if _, err := FromEnv("BADCACHE"); err == nil || !strings.Contains(err.Error(), "unknown config key CAPACTY") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+
+	os.Unsetenv("BADCACHE_BACKEND_INMEMORY_CAPACTY")
+	t.Setenv("BADCACHE_BACKEND", "x")
+	if _, err := FromEnv("BADCACHE"); err == nil || !strings.Contains(err.Error(), "backend needs a nested key") {
+		t.Errorf("expected nested key error, got %v", err)
+	}
+}
+
+func TestLoadMergeOrder(t *testing.T) {
+	path := writeConfigFile(t, "cache.yaml", `
+backendName: in-memory
+backend:
+  inMemory:
+    capacity: 100
+    shardCount: 4
+    ttl: 1m
+`)
+	t.Setenv("MERGECACHE_BACKEND_INMEMORY_CAPACITY", "200")
+	t.Setenv("MERGECACHE_BACKEND_INMEMORY_TTL", "2m")
+
+	cfg, err := Load(path, "MERGECACHE", func(cfg *InvaCacheConfig) {
+		cfg.Backend.InMemory.Ttl = "3m"
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inMemory := cfg.Backend.InMemory
+	if inMemory.ShardCount != 4 {
+		t.Errorf("expected file value to survive, got shard count %d", inMemory.ShardCount)
+	}
+	if inMemory.Capacity != 200 {
+		t.Errorf("expected env to override file, got capacity %d", inMemory.Capacity)
+	}
+	if inMemory.Ttl != "3m" {
+		t.Errorf("expected code override to win, got ttl %s", inMemory.Ttl)
+	}
+
+	t.Setenv("MERGECACHE_BACKEND_INMEMORY_CAPACITY", "2")
+	if _, err := Load(path, "MERGECACHE"); err == nil {
+		t.Error("expected merged config to be validated")
+	}
+}
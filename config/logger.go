@@ -0,0 +1,27 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+// NewLogger returns the logger a backend should use for component. Logger
+// takes precedence over LogHandler, which takes precedence over the default
+// JSON logger on stdout. LogLevel filters LogHandler and the default logger;
+// "off" silences all of them.
+func (cfg *InvaCacheConfig) NewLogger(component string) logger.Logger {
+	if strings.EqualFold(cfg.LogLevel, constant.LogLevelOff) {
+		return logger.Discard()
+	}
+	if cfg.Logger != nil {
+		return cfg.Logger.With("component", component)
+	}
+
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	if cfg.LogHandler != nil {
+		return logger.New(logger.WithLevel(cfg.LogHandler, level)).With("component", component)
+	}
+	return logger.NewLoggerWithLevel(component, level)
+}
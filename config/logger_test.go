@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+func TestNewLoggerUsesHandlerAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := InvaCacheConfig{
+		LogHandler: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		LogLevel:   "warn",
+	}
+
+	log := cfg.NewLogger("test-cache")
+	log.Info("dropped")
+	log.Warn("kept", "key", "a")
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("expected info record to be filtered, got %s", out)
+	}
+	if !strings.Contains(out, `"msg":"kept"`) || !strings.Contains(out, `"component":"test-cache"`) {
+		t.Errorf("expected warn record with component, got %s", out)
+	}
+}
+
+func TestNewLoggerPrefersLogger(t *testing.T) {
+	var fromLogger, fromHandler bytes.Buffer
+	cfg := InvaCacheConfig{
+		Logger:     logger.New(slog.NewTextHandler(&fromLogger, nil)),
+		LogHandler: slog.NewTextHandler(&fromHandler, nil),
+	}
+
+	cfg.NewLogger("test-cache").Info("hello")
+
+	if !strings.Contains(fromLogger.String(), "component=test-cache") {
+		t.Errorf("expected record on Logger, got %q", fromLogger.String())
+	}
+	if fromHandler.Len() != 0 {
+		t.Errorf("expected LogHandler to be unused, got %q", fromHandler.String())
+	}
+}
+
+func TestNewLoggerOff(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := InvaCacheConfig{
+		Logger:   logger.New(slog.NewTextHandler(&buf, nil)),
+		LogLevel: "OFF",
+	}
+
+	cfg.NewLogger("test-cache").Error("silenced")
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestValidateRejectsUnknownLogLevel(t *testing.T) {
+	cfg := InvaCacheConfig{LogLevel: "verbose"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown log level "verbose"`) {
+		t.Errorf("expected log level error, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]struct{}{
+		constant.InMemoryBackend: {},
+		constant.DiskBackend:     {},
+		constant.OffHeapBackend:  {},
+		constant.TieredBackend:   {},
+	}
+)
+
+// RegisterBackend makes name a valid backend name. It is safe for concurrent
+// use and fails if name is already taken, including by the built-in backends.
+func RegisterBackend(name string) error {
+	if name == constant.EmptyString {
+		return fmt.Errorf("backend registration needs a name")
+	}
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, exists := backends[name]; exists {
+		return fmt.Errorf("backend %s is already registered", name)
+	}
+	backends[name] = struct{}{}
+	return nil
+}
+
+func IsBackendRegistered(name string) bool {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	_, exists := backends[name]
+	return exists
+}
+
+// Validate reports every problem in the configuration as a single joined
+// error. Zero values are checked against the defaults ApplyDefaults would use.
+func (cfg *InvaCacheConfig) Validate() error {
+	var errs []error
+
+	if cfg.BackendName != constant.EmptyString && !IsBackendRegistered(cfg.BackendName) {
+		errs = append(errs, fmt.Errorf("unknown backend name %s", cfg.BackendName))
+	}
+	if !strings.EqualFold(cfg.LogLevel, constant.LogLevelOff) {
+		if _, err := logger.ParseLevel(cfg.LogLevel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.Backend != nil {
+		errs = append(errs, cfg.Backend.validate(cfg.BackendName)...)
+	}
+	if cfg.Invalidation != nil {
+		errs = append(errs, cfg.Invalidation.validate()...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (cfg *BackendConfig) validate(backendName string) []error {
+	var errs []error
+
+	if cfg.InMemory != nil {
+		errs = append(errs, cfg.InMemory.validate()...)
+	}
+	if cfg.Remote != nil {
+		errs = appendTTLError(errs, "remote ttl", cfg.Remote.Ttl)
+	}
+	if cfg.Tiered != nil {
+		errs = appendTTLError(errs, "l1 ttl", cfg.Tiered.L1Ttl)
+		if backendName == constant.TieredBackend {
+			l2 := cfg.Tiered.L2Backend
+			if l2 == constant.EmptyString {
+				l2 = constant.RedisBackend
+			}
+			if l2 == constant.TieredBackend || !IsBackendRegistered(l2) {
+				errs = append(errs, fmt.Errorf("unknown l2 backend %s", l2))
+			}
+		}
+	}
+	if cfg.Disk != nil {
+		errs = append(errs, cfg.Disk.validate(backendName)...)
+	}
+	if cfg.OffHeap != nil {
+		errs = append(errs, cfg.OffHeap.validate()...)
+	}
+
+	return errs
+}
+
+func (cfg *DiskConfig) validate(backendName string) []error {
+	var errs []error
+
+	if backendName == constant.DiskBackend && cfg.Dir == constant.EmptyString {
+		errs = append(errs, errors.New("disk backend requires a directory"))
+	}
+	maxBytes := positiveOr(cfg.MaxBytes, constant.DefaultDiskMaxBytes)
+	if maxBytes < constant.MinDiskMaxBytes {
+		errs = append(errs, fmt.Errorf("disk max bytes(%d) must be at least %d", maxBytes, constant.MinDiskMaxBytes))
+	}
+	errs = appendTTLError(errs, "disk ttl", cfg.Ttl)
+
+	return errs
+}
+
+// validate checks that every shard's ring can hold an entry: MaxBytes is
+// split evenly across the shards, and ring offsets are 32-bit.
+func (cfg *OffHeapConfig) validate() []error {
+	var errs []error
+
+	maxBytes := positiveOr(cfg.MaxBytes, constant.DefaultOffHeapMaxBytes)
+	shardCount := positiveOr(cfg.ShardCount, constant.DefaultShardCount)
+	shardBytes := maxBytes / int64(shardCount)
+	if shardBytes < constant.MinOffHeapShardBytes {
+		errs = append(errs, fmt.Errorf("off-heap max bytes(%d) across %d shards leaves %d bytes per shard, need at least %d",
+			maxBytes, shardCount, shardBytes, constant.MinOffHeapShardBytes))
+	}
+	if shardBytes > math.MaxUint32 {
+		errs = append(errs, fmt.Errorf("off-heap max bytes(%d) across %d shards leaves %d bytes per shard, at most %d are supported",
+			maxBytes, shardCount, shardBytes, uint32(math.MaxUint32)))
+	}
+	errs = appendTTLError(errs, "off-heap ttl", cfg.Ttl)
+
+	return errs
+}
+
+func (cfg *InMemoryConfig) validate() []error {
+	var errs []error
+
+	capacity := positiveOr(cfg.Capacity, constant.DefaultCapacity)
+	shardCount := positiveOr(cfg.ShardCount, constant.DefaultShardCount)
+	if capacity <= shardCount {
+		errs = append(errs, fmt.Errorf("shard count(%d) cannot be greater then or equal to capacity(%d)", shardCount, capacity))
+	}
+	errs = appendTTLError(errs, "ttl", cfg.Ttl)
+
+	if cfg.MemoryPressure != nil {
+		errs = append(errs, cfg.MemoryPressure.validate()...)
+	}
+
+	return errs
+}
+
+func (cfg *MemoryPressureConfig) validate() []error {
+	var errs []error
+
+	threshold := positiveOr(cfg.Threshold, constant.DefaultPressureThreshold)
+	recoverThreshold := positiveOr(cfg.RecoverThreshold, min(constant.DefaultPressureRecoverThreshold, threshold))
+	if recoverThreshold > threshold {
+		errs = append(errs, fmt.Errorf("recover threshold(%v) cannot be greater than threshold(%v)", recoverThreshold, threshold))
+	}
+	if cfg.ShedFraction > 1 {
+		errs = append(errs, fmt.Errorf("shed fraction(%v) must be within (0, 1]", cfg.ShedFraction))
+	}
+	if cfg.MinCapacityFraction > 1 {
+		errs = append(errs, fmt.Errorf("min capacity fraction(%v) must be within (0, 1]", cfg.MinCapacityFraction))
+	}
+
+	return errs
+}
+
+func (cfg *InvalidationConfig) validate() []error {
+	var errs []error
+	if cfg.Resubscribe != nil {
+		errs = append(errs, cfg.Resubscribe.validate()...)
+	}
+	if cfg.Publish != nil {
+		errs = append(errs, cfg.Publish.validate()...)
+	}
+	if cfg.Delivery != nil {
+		errs = append(errs, cfg.Delivery.validate()...)
+	}
+	if cfg.Envelope != nil && cfg.Envelope.DedupeWindow < 0 {
+		errs = append(errs, fmt.Errorf("dedupe window(%v) cannot be negative", cfg.Envelope.DedupeWindow))
+	}
+	if cfg.Dispatch != nil && (cfg.Dispatch.Workers < 0 || cfg.Dispatch.QueueSize < 0) {
+		errs = append(errs, fmt.Errorf("dispatch workers(%d) and queue size(%d) cannot be negative", cfg.Dispatch.Workers, cfg.Dispatch.QueueSize))
+	}
+	if cfg.PubSub != nil {
+		return errs
+	}
+	if cfg.Type == constant.EmptyString {
+		return append(errs, errors.New("invalidation type is required"))
+	}
+	if !invalidation.IsRegistered(cfg.Type) {
+		return append(errs, fmt.Errorf("invalidation driver %s is not registered, import its driver package", cfg.Type))
+	}
+	if err := invalidation.ValidateConfig(cfg.Type, cfg.DriverConfig); err != nil {
+		return append(errs, fmt.Errorf("invalid %s driver config: %w", cfg.Type, err))
+	}
+	return errs
+}
+
+func (cfg *ResubscribeConfig) validate() []error {
+	var errs []error
+
+	initial := positiveOr(int(cfg.InitialBackoff), int(constant.DefaultResubscribeInitialBackoff))
+	if cfg.MaxBackoff > 0 && int(cfg.MaxBackoff) < initial {
+		errs = append(errs, fmt.Errorf("max backoff(%v) cannot be less than initial backoff(%v)", cfg.MaxBackoff, time.Duration(initial)))
+	}
+	if cfg.Multiplier != 0 && cfg.Multiplier < 1 {
+		errs = append(errs, fmt.Errorf("backoff multiplier(%v) must be at least 1", cfg.Multiplier))
+	}
+	if cfg.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("backoff jitter(%v) must be at most 1, or negative to disable it", cfg.Jitter))
+	}
+
+	return errs
+}
+
+func (cfg *PublishConfig) validate() []error {
+	var errs []error
+
+	if cfg.Overflow != constant.EmptyString && !invalidation.OverflowPolicy(cfg.Overflow).Valid() {
+		errs = append(errs, fmt.Errorf("unknown publish overflow policy %q, expected block, drop-oldest or fail", cfg.Overflow))
+	}
+	if cfg.QueueSize < 0 {
+		errs = append(errs, fmt.Errorf("publish queue size(%d) cannot be negative", cfg.QueueSize))
+	}
+	if cfg.BatchSize < 0 || cfg.BatchWindow < 0 {
+		errs = append(errs, fmt.Errorf("publish batch size(%d) and window(%v) cannot be negative", cfg.BatchSize, cfg.BatchWindow))
+	}
+	retry := positiveOr(int(cfg.RetryBackoff), int(constant.DefaultPublishRetryBackoff))
+	if cfg.MaxRetryBackoff > 0 && int(cfg.MaxRetryBackoff) < retry {
+		errs = append(errs, fmt.Errorf("max retry backoff(%v) cannot be less than retry backoff(%v)", cfg.MaxRetryBackoff, time.Duration(retry)))
+	}
+
+	return errs
+}
+
+func (cfg *DeliveryConfig) validate() []error {
+	var errs []error
+	for _, value := range []string{cfg.Set, cfg.Delete, cfg.Clear} {
+		if _, err := option.ParseDelivery(value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func appendTTLError(errs []error, name, ttl string) []error {
+	if ttl == constant.EmptyString {
+		return errs
+	}
+	if _, err := time.ParseDuration(ttl); err != nil {
+		return append(errs, fmt.Errorf("invalid %s format '%s': %w", name, ttl, err))
+	}
+	return errs
+}
+
+func positiveOr[T int | int64 | float64](value, fallback T) T {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type noopPubSub struct{}
+
+func (noopPubSub) Publish(context.Context, string) error { return nil }
+
+func (noopPubSub) Subscribe(context.Context, invalidation.InvalidationHandler) error { return nil }
+
+func (noopPubSub) Close() error { return nil }
+
+func init() {
+	invalidation.RegisterInvalidatorWithLogger("validate-test", func(any, logger.Logger) (invalidation.PubSub, error) {
+		return noopPubSub{}, nil
+	})
+	invalidation.RegisterConfigValidator("validate-test", func(cfg map[string]any) error {
+		if _, ok := cfg["Address"].(string); !ok {
+			return errors.New("address is required")
+		}
+		return nil
+	})
+}
+
+func TestValidateValidConfig(t *testing.T) {
+	cfg := InvaCacheConfig{
+		BackendName: constant.InMemoryBackend,
+		Backend: &BackendConfig{
+			InMemory: &InMemoryConfig{Capacity: 100, ShardCount: 4, Ttl: "5m"},
+		},
+		Invalidation: &InvalidationConfig{
+			Type:         "validate-test",
+			DriverConfig: map[string]any{"Address": "localhost:6379"},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestValidateZeroValuesUseDefaults(t *testing.T) {
+	cfg := InvaCacheConfig{Backend: &BackendConfig{InMemory: &InMemoryConfig{Capacity: -1}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected non-positive values to fall back to defaults, got %v", err)
+	}
+}
+
+func TestValidateCollectsEveryProblem(t *testing.T) {
+	cfg := InvaCacheConfig{
+		BackendName: "unknown",
+		Backend: &BackendConfig{
+			InMemory: &InMemoryConfig{Capacity: 4, ShardCount: 8, Ttl: "soon"},
+			Remote:   &RemoteConfig{Ttl: "later"},
+		},
+		Invalidation: &InvalidationConfig{Type: "missing-driver"},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected a joined error, got %T", err)
+	}
+	if got := len(joined.Unwrap()); got != 5 {
+		t.Errorf("expected 5 problems, got %d: %v", got, err)
+	}
+
+	for _, want := range []string{
+		"unknown backend name unknown",
+		"shard count(8) cannot be greater then or equal to capacity(4)",
+		"invalid ttl format 'soon'",
+		"invalid remote ttl format 'later'",
+		"invalidation driver missing-driver is not registered",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestValidateInvalidationConfig(t *testing.T) {
+	cfg := InvaCacheConfig{Invalidation: &InvalidationConfig{}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalidation type is required") {
+		t.Errorf("expected missing type error, got %v", err)
+	}
+
+	cfg.Invalidation = &InvalidationConfig{Type: "validate-test", DriverConfig: map[string]any{}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid validate-test driver config: address is required") {
+		t.Errorf("expected malformed driver config error, got %v", err)
+	}
+}
+
+func TestValidateBackendSpecificRules(t *testing.T) {
+	cfg := InvaCacheConfig{
+		BackendName: constant.DiskBackend,
+		Backend:     &BackendConfig{Disk: &DiskConfig{}},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "disk backend requires a directory") {
+		t.Errorf("expected missing directory error, got %v", err)
+	}
+
+	cfg = InvaCacheConfig{
+		BackendName: constant.TieredBackend,
+		Backend:     &BackendConfig{Tiered: &TieredConfig{L2Backend: "validate-l2"}},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "unknown l2 backend validate-l2") {
+		t.Errorf("expected unknown l2 backend error, got %v", err)
+	}
+
+	if err := RegisterBackend("validate-l2"); err != nil {
+		t.Fatalf("unexpected error registering l2 backend: %v", err)
+	}
+	t.Cleanup(func() { unregisterBackend("validate-l2") })
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error after registering l2 backend: %v", err)
+	}
+}
+
+func TestRegisterBackendRejectsDuplicates(t *testing.T) {
+	if err := RegisterBackend(constant.InMemoryBackend); err == nil {
+		t.Error("expected registering a built-in backend name to fail")
+	}
+	if err := RegisterBackend(""); err == nil {
+		t.Error("expected registering an empty name to fail")
+	}
+
+	if err := RegisterBackend("validate-duplicate"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { unregisterBackend("validate-duplicate") })
+	if err := RegisterBackend("validate-duplicate"); err == nil {
+		t.Error("expected registering the same name twice to fail")
+	}
+}
+
+func unregisterBackend(name string) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	delete(backends, name)
+}
+
+func TestValidateOffHeapAndDiskSizes(t *testing.T) {
+	cfg := InvaCacheConfig{
+		BackendName: constant.OffHeapBackend,
+		Backend: &BackendConfig{
+			OffHeap: &OffHeapConfig{MaxBytes: 4 << 10, ShardCount: 64, Ttl: "soon"},
+			Disk:    &DiskConfig{Dir: "/tmp/cache", MaxBytes: 100},
+		},
+	}
+	err := cfg.Validate()
+	for _, want := range []string{"leaves 64 bytes per shard", "disk max bytes(100) must be at least", "off-heap ttl"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+
+	cfg.Backend.OffHeap = &OffHeapConfig{MaxBytes: 1 << 20, ShardCount: 4}
+	cfg.Backend.Disk = &DiskConfig{Dir: "/tmp/cache"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error for sane sizes: %v", err)
+	}
+}
+
+func TestValidateMemoryPressure(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Backend: &BackendConfig{
+			InMemory: &InMemoryConfig{
+				MemoryPressure: &MemoryPressureConfig{ShedFraction: 2, MinCapacityFraction: 1.5},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "shed fraction(2)") || !strings.Contains(err.Error(), "min capacity fraction(1.5)") {
+		t.Errorf("expected fraction errors, got %v", err)
+	}
+}
+
+func TestValidateResubscribe(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Invalidation: &InvalidationConfig{
+			Type:        "validate-test",
+			Resubscribe: &ResubscribeConfig{InitialBackoff: time.Second, MaxBackoff: time.Millisecond, Multiplier: 0.5, Jitter: 2},
+		},
+	}
+
+	err := cfg.Validate()
+	for _, want := range []string{"max backoff(1ms)", "multiplier(0.5)", "jitter(2)"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestValidatePublish(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Invalidation: &InvalidationConfig{
+			Type:    "validate-test",
+			Publish: &PublishConfig{Overflow: "spill", QueueSize: -1, RetryBackoff: time.Second, MaxRetryBackoff: time.Millisecond},
+		},
+	}
+
+	err := cfg.Validate()
+	for _, want := range []string{`overflow policy "spill"`, "queue size(-1)", "max retry backoff(1ms)"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestValidateDelivery(t *testing.T) {
+	cfg := InvaCacheConfig{
+		Invalidation: &InvalidationConfig{Type: "validate-test", Delivery: &DeliveryConfig{Delete: "async", Clear: "eventually"}},
+	}
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown delivery "eventually"`) {
+		t.Errorf("expected delivery error, got %v", err)
+	}
+	defaults := cfg.Invalidation.DeliveryDefaults()
+	if defaults.Set != option.DeliveryLocal || defaults.Delete != option.DeliveryAsync {
+		t.Errorf("unexpected defaults %+v", defaults)
+	}
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML understands the subset of YAML used by config files: nested
+// block mappings, block sequences of scalars, plain and quoted scalars and
+// comments. Anchors, flow collections, multi-line strings and multiple
+// documents are rejected.
+func parseYAML(data []byte) (map[string]any, error) {
+	p := &yamlParser{}
+	for idx, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \r")
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed for indentation", idx+1)
+		}
+		if trimmed == "---" && len(p.lines) == 0 {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(raw) - len(trimmed), text: trimmed, num: idx + 1})
+	}
+
+	if len(p.lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("yaml document must be a mapping")
+	}
+	return m, nil
+}
+
+type yamlLine struct {
+	text   string
+	indent int
+	num    int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+		if isSequenceItem(line.text) {
+			return nil, p.errorf(line, "unexpected list item in mapping")
+		}
+
+		key, rest, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		if _, exists := m[key]; exists {
+			return nil, p.errorf(line, "duplicate key %q", key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, p.errorf(line, "%v", err)
+			}
+			m[key] = value
+			continue
+		}
+
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+			value, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		} else if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+			value, err := p.parseSequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		} else {
+			m[key] = nil
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, "unexpected indentation")
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if _, _, err := splitYAMLKey(text); err == nil && text != "" && text[0] != '"' && text[0] != '\'' {
+			return nil, p.errorf(line, "mappings inside lists are not supported")
+		}
+		value, err := parseYAMLScalar(text)
+		if err != nil {
+			return nil, p.errorf(line, "%v", err)
+		}
+		items = append(items, value)
+		p.pos++
+	}
+	return items, nil
+}
+
+func (p *yamlParser) errorf(line yamlLine, format string, args ...any) error {
+	return fmt.Errorf("yaml line %d: %s", line.num, fmt.Sprintf(format, args...))
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func splitYAMLKey(text string) (string, string, error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		key := text[1 : end+1]
+		rest := text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", fmt.Errorf("expected ':' after key %q", key)
+		}
+		return key, valueOf(rest[1:]), nil
+	}
+
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", fmt.Errorf("expected 'key: value', got %q", text)
+		}
+		idx = len(text) - 1
+	}
+	return strings.TrimSpace(text[:idx]), valueOf(text[idx+1:]), nil
+}
+
+// valueOf trims the text after a key's colon. A value that is only a comment
+// is empty, so the key can still open a nested block.
+func valueOf(rest string) string {
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "#") {
+		return ""
+	}
+	return rest
+}
+
+func parseYAMLScalar(text string) (any, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '"':
+		end := closingQuote(text)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		if err := checkTrailing(text[end+1:]); err != nil {
+			return nil, err
+		}
+		return strconv.Unquote(text[:end+1])
+	case '\'':
+		value, end := unquoteSingle(text)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		if err := checkTrailing(text[end+1:]); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case '&', '*', '!', '|', '>', '[', '{':
+		switch text {
+		case "[]":
+			return []any{}, nil
+		case "{}":
+			return map[string]any{}, nil
+		}
+		return nil, fmt.Errorf("unsupported yaml syntax %q", text)
+	}
+
+	if idx := strings.Index(text, " #"); idx >= 0 {
+		text = strings.TrimSpace(text[:idx])
+	}
+	return inferScalar(text), nil
+}
+
+// inferScalar types an unquoted scalar the way YAML would.
+func inferScalar(text string) any {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.Atoi(text); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && strings.ContainsAny(text, ".eE") {
+		return f
+	}
+	return text
+}
+
+func closingQuote(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteSingle(text string) (string, int) {
+	var b strings.Builder
+	for i := 1; i < len(text); i++ {
+		if text[i] != '\'' {
+			b.WriteByte(text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		return b.String(), i
+	}
+	return "", -1
+}
+
+func checkTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected content after quoted string: %q", rest)
+	}
+	return nil
+}
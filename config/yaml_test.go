@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	doc := `---
+# cache settings
+backendName: in-memory
+backend:
+  inMemory:
+    capacity: 5000   # entries
+    sweeperInterval: 30s
+    ttl: "10m"
+  empty:
+invalidation:
+  type: 'redis'
+  driverConfig:
+    Address: redis://localhost:6379
+    Password: "p#ss word"
+    Quote: 'it''s'
+    DB: 2
+    Ratio: 0.5
+    Enabled: true
+    Nothing: ~
+    Hosts:
+      - a
+      - "b"
+`
+	got, err := parseYAML([]byte(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]any{
+		"backendName": "in-memory",
+		"backend": map[string]any{
+			"inMemory": map[string]any{"capacity": 5000, "sweeperInterval": "30s", "ttl": "10m"},
+			"empty":    nil,
+		},
+		"invalidation": map[string]any{
+			"type": "redis",
+			"driverConfig": map[string]any{
+				"Address":  "redis://localhost:6379",
+				"Password": "p#ss word",
+				"Quote":    "it's",
+				"DB":       2,
+				"Ratio":    0.5,
+				"Enabled":  true,
+				"Nothing":  nil,
+				"Hosts":    []any{"a", "b"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result:\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestParseYAMLSequenceAtParentIndent(t *testing.T) {
+	got, err := parseYAML([]byte("hosts:\n- a\n- b\nname: x\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got["hosts"], []any{"a", "b"}) || got["name"] != "x" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+}
+
+func TestParseYAMLCommentedParentKey(t *testing.T) {
+	doc := "invalidation: # redis\n  type: redis\n\"hosts\": # seeds\n- a\nempty: # nothing\n"
+	got, err := parseYAML([]byte(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"invalidation": map[string]any{"type": "redis"},
+		"hosts":        []any{"a"},
+		"empty":        nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result:\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\n  b: 2\n":          "line 2: unexpected indentation",
+		"a: 1\na: 2\n":            `line 2: duplicate key "a"`,
+		"a: &anchor x\n":          "unsupported yaml syntax",
+		"a: \"open\n":             "unterminated string",
+		"just text\n":             "expected 'key: value'",
+		"a:\n  - b: c\n":          "mappings inside lists are not supported",
+		"- a\n":                   "yaml document must be a mapping",
+		"a: \"x\" trailing\n":     "unexpected content after quoted string",
+		"a:\n\tb: 1\n":            "tabs are not allowed",
+		"a: [1, 2]\n":             "unsupported yaml syntax",
+		"a:\n  b: 1\n c: 2\n":     "unexpected indentation",
+		"a:\n  b: 1\n  - c\n":     "unexpected list item in mapping",
+		"'a: 1\n":                 "unterminated quoted key",
+		"\"a\" 1\n":               "expected ':' after key",
+		"a: |\n  multi\n  line\n": "unsupported yaml syntax",
+	}
+
+	for doc, want := range tests {
+		if _, err := parseYAML([]byte(doc)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseYAML(%q): expected error containing %q, got %v", doc, want, err)
+		}
+	}
+}
@@ -6,10 +6,68 @@ const (
 	DefaultCapacity        = 1000
 	DefaultShardCount      = 8
 	DefaultSweeperInterval = 10 * time.Minute
+	DefaultDiskMaxBytes    = 256 << 20
+	DefaultOffHeapMaxBytes = 64 << 20
+	MinOffHeapShardBytes   = 1 << 10
+	MinDiskMaxBytes        = 4 << 10
+)
+
+const (
+	DefaultLockTTL          = 10 * time.Second
+	DefaultLockWait         = 5 * time.Second
+	DefaultLockPollInterval = 50 * time.Millisecond
+	DefaultOperationTimeout = 3 * time.Second
+	DefaultL1TTL            = time.Minute
+)
+
+const (
+	DefaultPressureInterval         = time.Second
+	DefaultPressureThreshold        = 0.9
+	DefaultPressureRecoverThreshold = 0.75
+	DefaultShedFraction             = 0.1
+	DefaultMinCapacityFraction      = 0.25
+)
+
+const (
+	DefaultResubscribeInitialBackoff = 100 * time.Millisecond
+	DefaultResubscribeMaxBackoff     = 30 * time.Second
+	DefaultResubscribeMultiplier     = 2.0
+	DefaultResubscribeJitter         = 0.2
+)
+
+const (
+	DefaultPublishQueueSize       = 1024
+	DefaultPublishOverflow        = "drop-oldest"
+	DefaultPublishMaxRetries      = 3
+	DefaultPublishRetryBackoff    = 50 * time.Millisecond
+	DefaultPublishMaxRetryBackoff = 2 * time.Second
+	DefaultPublishBatchSize       = 500
+	DefaultCloseFlushTimeout      = 5 * time.Second
+)
+
+const (
+	DefaultDispatchWorkers   = 4
+	DefaultDispatchQueueSize = 1024
+	DefaultDedupeWindow      = time.Minute
 )
 
 const (
 	InMemoryBackend = "in-memory"
+	RedisBackend    = "redis"
+	TieredBackend   = "tiered"
+	DiskBackend     = "disk"
+	OffHeapBackend  = "off-heap"
+)
+
+const (
+	JSONCodec    = "json"
+	GobCodec     = "gob"
+	RawCodec     = "raw"
+	DefaultCodec = GobCodec
+)
+
+const (
+	LogLevelOff = "off"
 )
 
 const (
@@ -1,7 +1,19 @@
 package constant
 
+import "errors"
+
 const (
 	ErrKeyNotFound = "key not found"
+	ErrCacheClosed = "cache is closed"
+	ErrEpochStale  = "cache was cleared after the write's epoch"
+)
+
+var (
+	// ErrNotFound is returned as is by every backend's Get on a miss, without
+	// the key, so that misses do not allocate.
+	ErrNotFound   = errors.New(ErrKeyNotFound)
+	ErrClosed     = errors.New(ErrCacheClosed)
+	ErrStaleEpoch = errors.New(ErrEpochStale)
 )
 
 const (
@@ -4,16 +4,33 @@ import (
 	"fmt"
 
 	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/disk"
 	"github.com/halilbulentorhon/invacache-go/backend/inmemory"
+	"github.com/halilbulentorhon/invacache-go/backend/offheap"
+	"github.com/halilbulentorhon/invacache-go/backend/remote"
+	"github.com/halilbulentorhon/invacache-go/backend/tiered"
 	"github.com/halilbulentorhon/invacache-go/config"
 	"github.com/halilbulentorhon/invacache-go/constant"
 )
 
 func NewCache[V any](cfg config.InvaCacheConfig) (backend.Cache[V], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cache config: %w", err)
+	}
+
 	switch cfg.BackendName {
 	case constant.InMemoryBackend:
 		return inmemory.NewInMemoryBackend[V](cfg)
+	case constant.DiskBackend:
+		return disk.NewDiskBackend[V](cfg)
+	case constant.OffHeapBackend:
+		return offheap.NewOffHeapBackend[V](cfg)
+	case constant.TieredBackend:
+		return tiered.NewTieredBackend[V](cfg)
 	default:
+		if remote.IsRegistered(cfg.BackendName) {
+			return remote.NewRemoteBackend[V](cfg)
+		}
 		return nil, fmt.Errorf("unknown backend name %s", cfg.BackendName)
 	}
 }
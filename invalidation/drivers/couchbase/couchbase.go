@@ -6,48 +6,30 @@ import (
 	"github.com/halilbulentorhon/cb-pubsub/config"
 	"github.com/halilbulentorhon/cb-pubsub/pubsub"
 	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
 )
 
 func init() {
-	invalidation.RegisterInvalidator("couchbase", func(config any) (invalidation.PubSub, error) {
-		cfgMap, ok := config.(map[string]any)
-		if !ok {
-			return nil, fmt.Errorf("invalid config type for couchbase invalidator, expected map[string]any, got %T", config)
-		}
-
-		cfg := CouchbaseConfig{
-			ConnectionString: getString(cfgMap, "connectionString"),
-			Username:         getString(cfgMap, "username"),
-			Password:         getString(cfgMap, "password"),
-			BucketName:       getString(cfgMap, "bucketName"),
-			CollectionName:   getString(cfgMap, "collectionName"),
-			ScopeName:        getString(cfgMap, "scopeName"),
-			GroupName:        getString(cfgMap, "groupName"),
-		}
-
+	invalidation.RegisterTypedInvalidatorWithLogger("couchbase", func(cfg CouchbaseConfig, log logger.Logger) (invalidation.PubSub, error) {
+		cfg.Logger = log
 		return NewCouchbaseInvalidator(cfg)
 	})
 }
 
-func getString(m map[string]any, key string) string {
-	if val, ok := m[key].(string); ok {
-		return val
-	}
-	return ""
-}
-
 type CouchbaseConfig struct {
-	ConnectionString string `json:"connectionString"`
-	Username         string `json:"username"`
-	Password         string `json:"password"`
-	BucketName       string `json:"bucketName"`
-	CollectionName   string `json:"collectionName"`
-	ScopeName        string `json:"scopeName,omitempty"`
-	GroupName        string `json:"groupName,omitempty"`
+	ConnectionString string        `json:"connectionString" required:"true"`
+	Username         string        `json:"username"`
+	Password         string        `json:"password"`
+	BucketName       string        `json:"bucketName" required:"true"`
+	CollectionName   string        `json:"collectionName" required:"true"`
+	ScopeName        string        `json:"scopeName,omitempty"`
+	GroupName        string        `json:"groupName,omitempty"`
+	Logger           logger.Logger `json:"-"`
 }
 
 type CouchbaseInvalidator struct {
 	pubsub pubsub.PubSub[string]
+	logger logger.Logger
 }
 
 func NewCouchbaseInvalidator(cfg CouchbaseConfig) (invalidation.PubSub, error) {
@@ -57,6 +39,9 @@ func NewCouchbaseInvalidator(cfg CouchbaseConfig) (invalidation.PubSub, error) {
 	if cfg.GroupName == "" {
 		cfg.GroupName = "invacache"
 	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.NewLogger("couchbase-invalidator")
+	}
 
 	pubsubConfig := config.PubSubConfig{
 		CouchbaseConfig: config.CouchbaseConfig{
@@ -82,6 +67,7 @@ func NewCouchbaseInvalidator(cfg CouchbaseConfig) (invalidation.PubSub, error) {
 
 	return &CouchbaseInvalidator{
 		pubsub: ps,
+		logger: cfg.Logger.With("group", cfg.GroupName),
 	}, nil
 }
 
@@ -93,7 +79,7 @@ func (c *CouchbaseInvalidator) Subscribe(ctx context.Context, handler invalidati
 	return c.pubsub.Subscribe(ctx, func(messages []string) error {
 		for _, key := range messages {
 			if err := handler(key); err != nil {
-				fmt.Printf("Error processing invalidation for key %s: %v\n", key, err)
+				c.logger.Error("failed to process invalidation", "key", key, "error", err)
 			}
 		}
 		return nil
@@ -2,68 +2,41 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
 	"github.com/redis/go-redis/v9"
 )
 
 func init() {
-	invalidation.RegisterInvalidator("redis", func(config any) (invalidation.PubSub, error) {
-		cfgMap, ok := config.(map[string]any)
-		if !ok {
-			return nil, fmt.Errorf("invalid config type for redis invalidator, expected map[string]any, got %T", config)
-		}
-
-		cfg := RedisConfig{
-			Address:     getString(cfgMap, "Address"),
-			Password:    getString(cfgMap, "Password"),
-			DB:          getInt(cfgMap, "DB"),
-			Channel:     getString(cfgMap, "Channel"),
-			PoolSize:    getInt(cfgMap, "PoolSize"),
-			MaxRetries:  getInt(cfgMap, "MaxRetries"),
-			DialTimeout: getDuration(cfgMap, "DialTimeout"),
-		}
-
+	invalidation.RegisterTypedInvalidatorWithLogger("redis", func(cfg RedisConfig, log logger.Logger) (invalidation.PubSub, error) {
+		cfg.Logger = log
 		return NewRedisInvalidator(cfg)
 	})
 }
 
-func getString(m map[string]any, key string) string {
-	if val, ok := m[key].(string); ok {
-		return val
-	}
-	return ""
-}
-
-func getInt(m map[string]any, key string) int {
-	if val, ok := m[key].(int); ok {
-		return val
-	}
-	return 0
-}
-
-func getDuration(m map[string]any, key string) time.Duration {
-	if val, ok := m[key].(time.Duration); ok {
-		return val
-	}
-	return 0
-}
+// ErrResubscribed is returned by SubscribeReady when the connection to Redis
+// was lost and re-established, so invalidations may have been missed.
+var ErrResubscribed = errors.New("redis subscription was re-established after a connection loss")
 
 type RedisConfig struct {
-	Address     string        `json:"address"`
+	Address     string        `json:"address" required:"true"`
 	Password    string        `json:"password,omitempty"`
 	DB          int           `json:"db"`
 	Channel     string        `json:"channel,omitempty"`
 	PoolSize    int           `json:"poolSize,omitempty"`
 	MaxRetries  int           `json:"maxRetries,omitempty"`
 	DialTimeout time.Duration `json:"dialTimeout,omitempty"`
+	Logger      logger.Logger `json:"-"`
 }
 
 type RedisInvalidator struct {
 	client  *redis.Client
 	channel string
+	logger  logger.Logger
 }
 
 func NewRedisInvalidator(cfg RedisConfig) (invalidation.PubSub, error) {
@@ -79,6 +52,9 @@ func NewRedisInvalidator(cfg RedisConfig) (invalidation.PubSub, error) {
 	if cfg.DialTimeout <= 0 {
 		cfg.DialTimeout = 5 * time.Second
 	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.NewLogger("redis-invalidator")
+	}
 
 	rdb := redis.NewClient(&redis.Options{
 		Addr:        cfg.Address,
@@ -99,6 +75,7 @@ func NewRedisInvalidator(cfg RedisConfig) (invalidation.PubSub, error) {
 	return &RedisInvalidator{
 		client:  rdb,
 		channel: cfg.Channel,
+		logger:  cfg.Logger.With("channel", cfg.Channel),
 	}, nil
 }
 
@@ -111,22 +88,43 @@ func (r *RedisInvalidator) Publish(ctx context.Context, key string) error {
 }
 
 func (r *RedisInvalidator) Subscribe(ctx context.Context, handler invalidation.InvalidationHandler) error {
+	return r.SubscribeReady(ctx, handler, func() {})
+}
+
+// SubscribeReady waits for Redis to confirm the subscription before calling
+// ready and delivering messages. go-redis reconnects and resubscribes on its
+// own after a connection loss, and messages published in between are lost;
+// a repeated subscription confirmation is therefore returned as an error so
+// the coordinator treats it as a gap.
+func (r *RedisInvalidator) SubscribeReady(ctx context.Context, handler invalidation.InvalidationHandler, ready func()) error {
 	pubsub := r.client.Subscribe(ctx, r.channel)
 	defer pubsub.Close()
 
-	ch := pubsub.Channel()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", r.channel, err)
+	}
+	ready()
+
+	ch := pubsub.ChannelWithSubscriptions()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case msg := <-ch:
-			if msg == nil {
-				continue
+		case msg, ok := <-ch:
+			if !ok {
+				return errors.New("redis subscription channel closed")
 			}
 
-			if err := handler(msg.Payload); err != nil {
-				fmt.Printf("Error processing invalidation for key %s: %v\n", msg.Payload, err)
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				if msg.Kind == "subscribe" {
+					return ErrResubscribed
+				}
+			case *redis.Message:
+				if err := handler(msg.Payload); err != nil {
+					r.logger.Error("failed to process invalidation", "key", msg.Payload, "error", err)
+				}
 			}
 		}
 	}
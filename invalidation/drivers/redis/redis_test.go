@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+func newTestInvalidator(t *testing.T) (*RedisInvalidator, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	inv, err := NewRedisInvalidator(RedisConfig{Address: server.Addr(), Logger: logger.Discard()})
+	if err != nil {
+		t.Fatalf("unexpected error creating invalidator: %v", err)
+	}
+	t.Cleanup(func() { _ = inv.Close() })
+	return inv.(*RedisInvalidator), server
+}
+
+func TestSubscribeReadyReportsReconnectAsGap(t *testing.T) {
+	inv, server := newTestInvalidator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready := make(chan struct{})
+	received := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- inv.SubscribeReady(ctx, func(key string) error {
+			received <- key
+			return nil
+		}, func() { close(ready) })
+	}()
+
+	<-ready
+	if err := inv.Publish(ctx, "user:1"); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if key := <-received; key != "user:1" {
+		t.Errorf("expected user:1, got %q", key)
+	}
+
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatalf("failed to restart redis: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrResubscribed) {
+			t.Errorf("expected ErrResubscribed, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected SubscribeReady to return after redis restarted")
+	}
+}
+
+func TestBatchIsPublishedAsOneMessage(t *testing.T) {
+	inv, _ := newTestInvalidator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready := make(chan struct{})
+	raw := make(chan string, 4)
+	go func() {
+		_ = inv.SubscribeReady(ctx, func(payload string) error {
+			raw <- payload
+			return nil
+		}, func() { close(ready) })
+	}()
+	<-ready
+
+	c := invalidation.NewCoordinator(ctx, inv, logger.Discard(),
+		invalidation.WithPublishQueue(invalidation.QueueConfig{BatchSize: 2, BatchWindow: time.Hour}))
+	go c.RunPublisher()
+	_ = c.Publish(invalidation.Message{Key: "user:1"})
+	_ = c.Publish(invalidation.Message{Key: "user:2"})
+
+	messages := invalidation.DecodeMessages(<-raw)
+	if len(messages) != 2 || messages[0].Key != "user:1" || messages[1].Key != "user:2" {
+		t.Errorf("expected one message carrying both keys, got %+v", messages)
+	}
+	select {
+	case extra := <-raw:
+		t.Errorf("expected a single message, also got %q", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
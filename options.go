@@ -0,0 +1,172 @@
+package invacache
+
+import (
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend"
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/remote"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type Option func(*settings)
+
+type settings struct {
+	cfg        config.InvaCacheConfig
+	defaultTTL *time.Duration
+	shards     int
+	capacity   int
+	maxBytes   int64
+}
+
+// New builds a cache from options. Without WithConfig or WithBackend it uses
+// the in-memory backend; options are applied in order on top of WithConfig.
+func New[V any](opts ...Option) (backend.Cache[V], error) {
+	s := settings{cfg: config.InvaCacheConfig{BackendName: constant.InMemoryBackend}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return NewCache[V](s.config())
+}
+
+// WithConfig uses cfg as the base configuration. It is not modified.
+func WithConfig(cfg config.InvaCacheConfig) Option {
+	return func(s *settings) {
+		if cfg.BackendName == constant.EmptyString {
+			cfg.BackendName = s.cfg.BackendName
+		}
+		s.cfg = cfg
+	}
+}
+
+func WithBackend(name string) Option {
+	return func(s *settings) {
+		s.cfg.BackendName = name
+	}
+}
+
+func WithCodec(name string) Option {
+	return func(s *settings) {
+		s.cfg.Codec = name
+	}
+}
+
+func WithShards(shards int) Option {
+	return func(s *settings) {
+		s.shards = shards
+	}
+}
+
+// WithCapacity sets the number of entries the in-memory backend, and the
+// tiered backend's in-memory L1, holds. The disk and off-heap backends are
+// bounded in bytes instead; see WithMaxBytes.
+func WithCapacity(capacity int) Option {
+	return func(s *settings) {
+		s.capacity = capacity
+	}
+}
+
+// WithMaxBytes sets the size in bytes of the disk and off-heap backends. It
+// does not apply to the in-memory backend; see WithCapacity.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(s *settings) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithDefaultTTL sets the TTL of entries stored without an explicit one on
+// every configured backend.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		s.defaultTTL = &ttl
+	}
+}
+
+// WithInvalidator uses an already built PubSub instead of a registered
+// driver. The cache closes it on Close.
+func WithInvalidator(pubsub invalidation.PubSub) Option {
+	return func(s *settings) {
+		inv := config.InvalidationConfig{}
+		if s.cfg.Invalidation != nil {
+			inv = *s.cfg.Invalidation
+		}
+		inv.PubSub = pubsub
+		s.cfg.Invalidation = &inv
+	}
+}
+
+func WithLogger(log logger.Logger) Option {
+	return func(s *settings) {
+		s.cfg.Logger = log
+	}
+}
+
+func WithClock(clk clock.Clock) Option {
+	return func(s *settings) {
+		s.cfg.Clock = clk
+	}
+}
+
+func (s *settings) config() config.InvaCacheConfig {
+	cfg := s.cfg
+	backendCfg := config.BackendConfig{}
+	if cfg.Backend != nil {
+		backendCfg = *cfg.Backend
+	}
+	cfg.Backend = &backendCfg
+
+	inMemory := config.InMemoryConfig{}
+	if backendCfg.InMemory != nil {
+		inMemory = *backendCfg.InMemory
+	}
+	backendCfg.InMemory = &inMemory
+
+	if s.shards > 0 {
+		inMemory.ShardCount = s.shards
+		if backendCfg.OffHeap != nil || cfg.BackendName == constant.OffHeapBackend {
+			backendCfg.OffHeap = cloneOrNew(backendCfg.OffHeap)
+			backendCfg.OffHeap.ShardCount = s.shards
+		}
+	}
+	if s.capacity > 0 {
+		inMemory.Capacity = s.capacity
+	}
+	if s.maxBytes > 0 {
+		if backendCfg.Disk != nil || cfg.BackendName == constant.DiskBackend {
+			backendCfg.Disk = cloneOrNew(backendCfg.Disk)
+			backendCfg.Disk.MaxBytes = s.maxBytes
+		}
+		if backendCfg.OffHeap != nil || cfg.BackendName == constant.OffHeapBackend {
+			backendCfg.OffHeap = cloneOrNew(backendCfg.OffHeap)
+			backendCfg.OffHeap.MaxBytes = s.maxBytes
+		}
+	}
+	if s.defaultTTL != nil {
+		ttl := s.defaultTTL.String()
+		inMemory.Ttl = ttl
+		if backendCfg.Disk != nil || cfg.BackendName == constant.DiskBackend {
+			backendCfg.Disk = cloneOrNew(backendCfg.Disk)
+			backendCfg.Disk.Ttl = ttl
+		}
+		if backendCfg.OffHeap != nil || cfg.BackendName == constant.OffHeapBackend {
+			backendCfg.OffHeap = cloneOrNew(backendCfg.OffHeap)
+			backendCfg.OffHeap.Ttl = ttl
+		}
+		if backendCfg.Remote != nil || remote.IsRegistered(cfg.BackendName) {
+			backendCfg.Remote = cloneOrNew(backendCfg.Remote)
+			backendCfg.Remote.Ttl = ttl
+		}
+	}
+	return cfg
+}
+
+func cloneOrNew[T any](src *T) *T {
+	dst := new(T)
+	if src != nil {
+		*dst = *src
+	}
+	return dst
+}
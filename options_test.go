@@ -0,0 +1,149 @@
+package invacache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/backend/invalidation"
+	"github.com/halilbulentorhon/invacache-go/backend/option"
+	"github.com/halilbulentorhon/invacache-go/config"
+	"github.com/halilbulentorhon/invacache-go/constant"
+	"github.com/halilbulentorhon/invacache-go/pkg/clock/clocktest"
+	"github.com/halilbulentorhon/invacache-go/pkg/logger"
+)
+
+type recordingPubSub struct {
+	mu        sync.Mutex
+	published []string
+	closed    bool
+}
+
+func (p *recordingPubSub) Publish(_ context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, key)
+	return nil
+}
+
+func (p *recordingPubSub) Subscribe(ctx context.Context, _ invalidation.InvalidationHandler) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *recordingPubSub) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func TestNewAppliesOptions(t *testing.T) {
+	clk := clocktest.NewFake(time.Unix(1000, 0))
+	cache, err := New[string](
+		WithShards(1),
+		WithCapacity(2),
+		WithDefaultTTL(time.Minute),
+		WithClock(clk),
+		WithLogger(logger.Discard()),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(key, key); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expected capacity 2 to hold 2 entries, got %d", n)
+	}
+
+	clk.Advance(59 * time.Second)
+	if _, err := cache.Get("c"); err != nil {
+		t.Errorf("expected c before default ttl, got %v", err)
+	}
+	clk.Advance(2 * time.Second)
+	if _, err := cache.Get("c"); !errors.Is(err, constant.ErrNotFound) {
+		t.Errorf("expected c to expire after default ttl, got %v", err)
+	}
+}
+
+func TestNewWithInvalidator(t *testing.T) {
+	pubsub := &recordingPubSub{}
+	cache, err := New[string](WithInvalidator(pubsub), WithLogger(logger.Discard()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cache.Set("k", "v", option.WithInvalidation()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	pubsub.mu.Lock()
+	defer pubsub.mu.Unlock()
+	if len(pubsub.published) != 1 || pubsub.published[0] != "k" {
+		t.Errorf("expected injected pubsub to receive k, got %v", pubsub.published)
+	}
+	if !pubsub.closed {
+		t.Error("expected cache to close the injected pubsub")
+	}
+}
+
+func TestNewWithConfigDoesNotModifyConfig(t *testing.T) {
+	cfg := config.InvaCacheConfig{
+		Backend: &config.BackendConfig{InMemory: &config.InMemoryConfig{Capacity: 50, ShardCount: 2}},
+		Logger:  logger.Discard(),
+	}
+
+	cache, err := New[string](WithConfig(cfg), WithCapacity(10), WithDefaultTTL(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Close()
+
+	if cfg.Backend.InMemory.Capacity != 50 || cfg.Backend.InMemory.Ttl != "" || cfg.Invalidation != nil {
+		t.Errorf("expected caller config to be untouched, got %+v", cfg.Backend.InMemory)
+	}
+}
+
+func TestCapacityAndMaxBytesApplyToTheirBackends(t *testing.T) {
+	for _, name := range []string{constant.DiskBackend, constant.OffHeapBackend} {
+		s := settings{cfg: config.InvaCacheConfig{BackendName: name}}
+		WithCapacity(10)(&s)
+		WithMaxBytes(1 << 20)(&s)
+		cfg := s.config()
+
+		if cfg.Backend.InMemory.Capacity != 10 {
+			t.Errorf("%s: expected the in-memory capacity to be set, got %d", name, cfg.Backend.InMemory.Capacity)
+		}
+		var maxBytes int64
+		if name == constant.DiskBackend {
+			maxBytes = cfg.Backend.Disk.MaxBytes
+		} else {
+			maxBytes = cfg.Backend.OffHeap.MaxBytes
+		}
+		if maxBytes != 1<<20 {
+			t.Errorf("%s: expected max bytes to be set, got %d", name, maxBytes)
+		}
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	_, err := New[string](WithShards(16), WithCapacity(8), WithLogger(logger.Discard()))
+	if err == nil {
+		t.Fatal("expected shard count error")
+	}
+
+	_, err = New[string](WithBackend("missing"), WithLogger(logger.Discard()))
+	if err == nil {
+		t.Fatal("expected unknown backend error")
+	}
+}
@@ -0,0 +1,105 @@
+// Package clocktest provides a manually advanced clock for tests.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/halilbulentorhon/invacache-go/pkg/clock"
+)
+
+// Fake is a clock.Clock whose time only moves on Advance or Set. Tickers fire
+// when the clock passes their next deadline; like time.Ticker, ticks are
+// dropped while the previous one has not been received.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, ch: make(chan time.Time, 1), interval: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.setLocked(f.now.Add(d))
+	f.mu.Unlock()
+}
+
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	f.setLocked(t)
+	f.mu.Unlock()
+}
+
+// Tickers reports how many tickers are running, so tests can wait for
+// background goroutines to start before advancing.
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	if t.Before(f.now) {
+		return
+	}
+	f.now = t
+	for _, ticker := range f.tickers {
+		if t.Before(ticker.next) {
+			continue
+		}
+		select {
+		case ticker.ch <- t:
+		default:
+		}
+		for !t.Before(ticker.next) {
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+func (f *Fake) remove(t *fakeTicker) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for idx, ticker := range f.tickers {
+		if ticker == t {
+			f.tickers = append(f.tickers[:idx], f.tickers[idx+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock    *Fake
+	ch       chan time.Time
+	next     time.Time
+	interval time.Duration
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t)
+}
@@ -0,0 +1,60 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Unix(100, 0)
+	clk := NewFake(start)
+
+	clk.Advance(time.Minute)
+	if got := clk.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected %v, got %v", start.Add(time.Minute), got)
+	}
+
+	clk.Set(start)
+	if got := clk.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Error("expected Set to ignore times in the past")
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+	ticker := clk.NewTicker(10 * time.Second)
+
+	clk.Advance(9 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its interval")
+	default:
+	}
+
+	clk.Advance(time.Second)
+	select {
+	case at := <-ticker.C():
+		if !at.Equal(time.Unix(10, 0)) {
+			t.Errorf("expected tick at 10s, got %v", at)
+		}
+	default:
+		t.Fatal("expected ticker to fire")
+	}
+
+	clk.Advance(35 * time.Second)
+	clk.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("expected ticks to be dropped while the channel is full")
+	default:
+	}
+
+	if clk.Tickers() != 1 {
+		t.Errorf("expected 1 ticker, got %d", clk.Tickers())
+	}
+	ticker.Stop()
+	if clk.Tickers() != 0 {
+		t.Errorf("expected stopped ticker to be removed, got %d", clk.Tickers())
+	}
+}
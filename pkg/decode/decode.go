@@ -0,0 +1,471 @@
+package decode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Into copies a generic value tree, as produced by encoding/json, the YAML
+// loader or environment parsing, into the value dst points to. Struct fields
+// are matched by their json tag, case-insensitively, and keys without a
+// matching field are ignored. Strings are parsed into numbers, booleans and
+// durations; numbers given for a duration are nanoseconds.
+func Into(src any, dst any) error {
+	return decoder{}.into(src, dst)
+}
+
+// Strict works like Into but rejects keys that do not match any field.
+func Strict(src any, dst any) error {
+	return decoder{strict: true}.into(src, dst)
+}
+
+// Config decodes a driver config into C. raw may be a C or *C built in code,
+// a map[string]any read from a config file, or nil. Unknown keys are
+// rejected and every field tagged required:"true" must be set to a non-zero
+// value.
+func Config[C any](raw any) (C, error) {
+	var cfg C
+	var decodeErr error
+	switch val := raw.(type) {
+	case C:
+		cfg = val
+	case *C:
+		if val != nil {
+			cfg = *val
+		}
+	case map[string]any:
+		decodeErr = Strict(val, &cfg)
+	case nil:
+	default:
+		return cfg, fmt.Errorf("expected map[string]any or %T, got %T", cfg, raw)
+	}
+
+	return cfg, errors.Join(decodeErr, checkRequired(reflect.ValueOf(&cfg).Elem(), ""))
+}
+
+type decoder struct {
+	strict bool
+}
+
+func (d decoder) into(src any, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("decode target must be a non-nil pointer")
+	}
+	return d.decodeValue(src, rv.Elem(), "")
+}
+
+// Normalize converts json.Number values inside an untyped tree to int when
+// they are integral and to float64 otherwise.
+func Normalize(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]any:
+		for k, item := range val {
+			val[k] = Normalize(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = Normalize(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func (d decoder) decodeValue(src any, dst reflect.Value, path string) error {
+	if src == nil {
+		return nil
+	}
+
+	if dst.Type() == durationType {
+		duration, err := toDuration(src)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetInt(int64(duration))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.decodeValue(src, dst.Elem(), path)
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return fieldError(path, fmt.Errorf("expected an object, got %T", src))
+		}
+		return d.decodeStruct(m, dst, path)
+	case reflect.Map:
+		return d.decodeMap(src, dst, path)
+	case reflect.Slice:
+		return d.decodeSlice(src, dst, path)
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(Normalize(src)))
+		return nil
+	case reflect.String:
+		s, err := toString(src)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := toBool(src)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(src)
+		if err == nil && dst.OverflowInt(i) {
+			err = fmt.Errorf("%d overflows %s", i, dst.Type())
+		}
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(src)
+		if err == nil && (i < 0 || dst.OverflowUint(uint64(i))) {
+			err = fmt.Errorf("%d overflows %s", i, dst.Type())
+		}
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(src)
+		if err != nil {
+			return fieldError(path, err)
+		}
+		dst.SetFloat(f)
+		return nil
+	default:
+		return fieldError(path, fmt.Errorf("unsupported type %s", dst.Type()))
+	}
+}
+
+func (d decoder) decodeStruct(src map[string]any, dst reflect.Value, path string) error {
+	var errs []error
+	for key, value := range src {
+		field, name, ok := findField(dst, key)
+		if !ok {
+			if d.strict {
+				errs = append(errs, fieldError(path, unknownFieldError(dst.Type(), key)))
+			}
+			continue
+		}
+		if err := d.decodeValue(value, field, joinPath(path, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func findField(dst reflect.Value, key string) (reflect.Value, string, bool) {
+	t := dst.Type()
+	var folded reflect.Value
+	var foldedName string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, ok := FieldName(sf)
+		if !ok {
+			continue
+		}
+		if name == key {
+			return dst.Field(i), name, true
+		}
+		if !folded.IsValid() && strings.EqualFold(name, key) {
+			folded, foldedName = dst.Field(i), name
+		}
+	}
+	return folded, foldedName, folded.IsValid()
+}
+
+// FieldName returns the key a struct field is decoded from: its json tag name,
+// or the field name when the tag has none. Unexported fields and fields tagged
+// "-" are skipped.
+func FieldName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return sf.Name, true
+}
+
+func (d decoder) decodeMap(src any, dst reflect.Value, path string) error {
+	if dst.Type().Key().Kind() != reflect.String {
+		return fieldError(path, fmt.Errorf("unsupported map key type %s", dst.Type().Key()))
+	}
+	m, ok := src.(map[string]any)
+	if !ok {
+		return fieldError(path, fmt.Errorf("expected an object, got %T", src))
+	}
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+	}
+
+	var errs []error
+	elemType := dst.Type().Elem()
+	for key, value := range m {
+		elem := reflect.New(elemType).Elem()
+		if err := d.decodeValue(value, elem, joinPath(path, key)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dst.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+	}
+	return errors.Join(errs...)
+}
+
+func (d decoder) decodeSlice(src any, dst reflect.Value, path string) error {
+	items, ok := src.([]any)
+	if !ok {
+		return fieldError(path, fmt.Errorf("expected a list, got %T", src))
+	}
+
+	slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := d.decodeValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	dst.Set(slice)
+	return nil
+}
+
+func unknownFieldError(t reflect.Type, key string) error {
+	best, bestDistance := "", 3
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := FieldName(t.Field(i))
+		if !ok {
+			continue
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(key)); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown field %q, did you mean %q", key, best)
+	}
+	return fmt.Errorf("unknown field %q", key)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func checkRequired(v reflect.Value, path string) error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, ok := FieldName(sf)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		fieldPath := joinPath(path, name)
+		if sf.Tag.Get("required") == "true" && field.IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required", fieldPath))
+			continue
+		}
+
+		if field.Kind() == reflect.Pointer && !field.IsNil() {
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct && field.Type() != durationType {
+			if err := checkRequired(field, fieldPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func toDuration(src any) (time.Duration, error) {
+	switch val := src.(type) {
+	case time.Duration:
+		return val, nil
+	case string:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", val)
+		}
+		return d, nil
+	default:
+		i, err := toInt64(src)
+		if err != nil {
+			return 0, fmt.Errorf("expected a duration, got %T", src)
+		}
+		return time.Duration(i), nil
+	}
+}
+
+func toInt64(src any) (int64, error) {
+	switch val := src.(type) {
+	case int:
+		return int64(val), nil
+	case int8:
+		return int64(val), nil
+	case int16:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case uint:
+		return int64(val), nil
+	case uint8:
+		return int64(val), nil
+	case uint16:
+		return int64(val), nil
+	case uint32:
+		return int64(val), nil
+	case uint64:
+		if val > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", val)
+		}
+		return int64(val), nil
+	case float32:
+		return floatToInt64(float64(val))
+	case float64:
+		return floatToInt64(val)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", val.String())
+		}
+		return floatToInt64(f)
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q", val)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", src)
+	}
+}
+
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("%v is not an integer", f)
+	}
+	return int64(f), nil
+}
+
+func toFloat64(src any) (float64, error) {
+	switch val := src.(type) {
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case json.Number:
+		return val.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", val)
+		}
+		return f, nil
+	default:
+		i, err := toInt64(src)
+		if err != nil {
+			return 0, fmt.Errorf("expected a number, got %T", src)
+		}
+		return float64(i), nil
+	}
+}
+
+func toBool(src any) (bool, error) {
+	switch val := src.(type) {
+	case bool:
+		return val, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(val))
+		if err != nil {
+			return false, fmt.Errorf("invalid boolean %q", val)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("expected a boolean, got %T", src)
+	}
+}
+
+func toString(src any) (string, error) {
+	switch val := src.(type) {
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(val), nil
+	default:
+		return "", fmt.Errorf("expected a string, got %T", src)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func fieldError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
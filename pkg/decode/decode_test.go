@@ -0,0 +1,185 @@
+package decode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nested struct {
+	Interval time.Duration `json:"interval"`
+	Ratio    float64       `json:"ratio"`
+}
+
+type target struct {
+	Extra    map[string]any    `json:"extra"`
+	Nested   *nested           `json:"nested"`
+	Labels   map[string]string `json:"labels"`
+	Name     string            `json:"name"`
+	Tags     []string          `json:"tags"`
+	Skipped  string            `json:"-"`
+	Timeout  time.Duration     `json:"timeout"`
+	Limit    uint64            `json:"limit"`
+	Count    int               `json:"count"`
+	Small    int8              `json:"small"`
+	Enabled  bool              `json:"enabled"`
+	NoTagged string
+}
+
+func TestIntoCoercesScalars(t *testing.T) {
+	src := map[string]any{
+		"name":     12345,
+		"timeout":  "30s",
+		"limit":    "1024",
+		"count":    json.Number("7"),
+		"enabled":  "true",
+		"NOTAGGED": "folded",
+		"nested":   map[string]any{"interval": json.Number("1000000000"), "ratio": "0.5"},
+		"labels":   map[string]any{"env": "prod"},
+		"tags":     []any{"a", 1},
+		"extra":    map[string]any{"db": json.Number("2"), "ratio": json.Number("0.25")},
+		"-":        "ignored",
+		"unknown":  "ignored",
+	}
+
+	var dst target
+	if err := Into(src, &dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if dst.Name != "12345" || dst.Timeout != 30*time.Second || dst.Limit != 1024 || dst.Count != 7 || !dst.Enabled {
+		t.Errorf("unexpected scalars: %+v", dst)
+	}
+	if dst.NoTagged != "folded" {
+		t.Errorf("expected case-insensitive match on field name, got %q", dst.NoTagged)
+	}
+	if dst.Nested == nil || dst.Nested.Interval != time.Second || dst.Nested.Ratio != 0.5 {
+		t.Errorf("unexpected nested value: %+v", dst.Nested)
+	}
+	if dst.Labels["env"] != "prod" || len(dst.Tags) != 2 || dst.Tags[1] != "1" {
+		t.Errorf("unexpected collections: %v %v", dst.Labels, dst.Tags)
+	}
+	if dst.Extra["db"] != 2 || dst.Extra["ratio"] != 0.25 {
+		t.Errorf("expected untyped numbers to be normalized, got %#v", dst.Extra)
+	}
+	if dst.Skipped != "" {
+		t.Error("expected fields tagged '-' to be skipped")
+	}
+}
+
+func TestIntoMergesIntoExistingValues(t *testing.T) {
+	dst := target{Name: "keep", Nested: &nested{Ratio: 0.1}}
+	if err := Into(map[string]any{"nested": map[string]any{"interval": "1m"}}, &dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.Name != "keep" || dst.Nested.Ratio != 0.1 || dst.Nested.Interval != time.Minute {
+		t.Errorf("expected existing values to be kept, got %+v %+v", dst, dst.Nested)
+	}
+}
+
+func TestIntoReportsFieldPaths(t *testing.T) {
+	src := map[string]any{
+		"count":  "many",
+		"small":  300,
+		"limit":  -1,
+		"nested": map[string]any{"interval": "soon"},
+		"tags":   "not-a-list",
+	}
+
+	var dst target
+	err := Into(src, &dst)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`count: invalid integer "many"`,
+		"small: 300 overflows int8",
+		"limit: -1 overflows uint64",
+		`nested.interval: invalid duration "soon"`,
+		"tags: expected a list",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestIntoRejectsNonPointer(t *testing.T) {
+	if err := Into(map[string]any{}, target{}); err == nil {
+		t.Error("expected error for non-pointer target")
+	}
+}
+
+type driverConfig struct {
+	Address     string        `json:"address" required:"true"`
+	Password    string        `json:"password,omitempty"`
+	DB          int           `json:"db"`
+	PoolSize    int           `json:"poolSize,omitempty"`
+	DialTimeout time.Duration `json:"dialTimeout,omitempty"`
+}
+
+func TestStrictRejectsUnknownFields(t *testing.T) {
+	var dst driverConfig
+	err := Strict(map[string]any{"Adress": "localhost", "verbose": true}, &dst)
+	if err == nil {
+		t.Fatal("expected error for unknown fields")
+	}
+	if !strings.Contains(err.Error(), `unknown field "Adress", did you mean "address"`) {
+		t.Errorf("expected suggestion for misspelled key, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `unknown field "verbose"`) {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestConfigDecodesJSONValues(t *testing.T) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(`{"Address": "redis:6379", "DB": 2, "PoolSize": 20.0, "DialTimeout": "2s"}`), &raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := Config[driverConfig](raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Address != "redis:6379" || cfg.DB != 2 || cfg.PoolSize != 20 || cfg.DialTimeout != 2*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	if _, err := Config[driverConfig](map[string]any{"Address": "x", "DB": 1.5}); err == nil || !strings.Contains(err.Error(), "db: 1.5 is not an integer") {
+		t.Errorf("expected fractional number error, got %v", err)
+	}
+}
+
+func TestConfigRequiredFields(t *testing.T) {
+	if _, err := Config[driverConfig](nil); err == nil || !strings.Contains(err.Error(), "address is required") {
+		t.Errorf("expected required error for nil config, got %v", err)
+	}
+	if _, err := Config[driverConfig](map[string]any{"Address": ""}); err == nil || !strings.Contains(err.Error(), "address is required") {
+		t.Errorf("expected required error for empty value, got %v", err)
+	}
+
+	type outer struct {
+		Inner *driverConfig `json:"inner"`
+	}
+	if _, err := Config[outer](map[string]any{"inner": map[string]any{}}); err == nil || !strings.Contains(err.Error(), "inner.address is required") {
+		t.Errorf("expected nested required error, got %v", err)
+	}
+}
+
+func TestConfigAcceptsTypedValues(t *testing.T) {
+	cfg, err := Config[driverConfig](driverConfig{Address: "typed"})
+	if err != nil || cfg.Address != "typed" {
+		t.Errorf("expected typed config to pass through, got %+v (%v)", cfg, err)
+	}
+
+	cfg, err = Config[driverConfig](&driverConfig{Address: "pointer"})
+	if err != nil || cfg.Address != "pointer" {
+		t.Errorf("expected pointer config to pass through, got %+v (%v)", cfg, err)
+	}
+
+	if _, err := Config[driverConfig]("address=x"); err == nil {
+		t.Error("expected error for unsupported config type")
+	}
+}
@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 )
 
 type Logger interface {
@@ -38,13 +41,77 @@ func (s *slogWrapper) With(args ...any) Logger {
 }
 
 func NewLogger(component string) Logger {
+	return NewLoggerWithLevel(component, slog.LevelInfo)
+}
+
+func NewLoggerWithLevel(component string, level slog.Leveler) Logger {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: level,
 	})).With("component", component)
 
 	return &slogWrapper{logger: logger}
 }
 
+func New(handler slog.Handler) Logger {
+	return &slogWrapper{logger: slog.New(handler)}
+}
+
+func Discard() Logger {
+	return New(discardHandler{})
+}
+
+// WithLevel drops records below level before they reach handler.
+func WithLevel(handler slog.Handler, level slog.Leveler) slog.Handler {
+	return &levelHandler{handler: handler, level: level}
+}
+
+// ParseLevel accepts debug, info, warn, warning and error, case-insensitively.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+type levelHandler struct {
+	handler slog.Handler
+	level   slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{handler: h.handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{handler: h.handler.WithGroup(name), level: h.level}
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool { return false }
+
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h discardHandler) WithGroup(string) slog.Handler { return h }
+
 func NewDevLogger(component string) Logger {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
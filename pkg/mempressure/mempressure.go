@@ -0,0 +1,76 @@
+package mempressure
+
+import (
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// The live heap is measured at the end of each GC cycle, so unlike the heap
+// object bytes it does not count garbage that has not been swept yet.
+const (
+	liveHeapMetric = "/gc/heap/live:bytes"
+	gcCyclesMetric = "/gc/cycles/total:gc-cycles"
+)
+
+type Sample struct {
+	HeapBytes uint64
+	Limit     uint64
+	// GCCycles is the number of completed GC cycles. HeapBytes only changes
+	// when it does.
+	GCCycles uint64
+}
+
+func (s Sample) Ratio() float64 {
+	if s.Limit == 0 {
+		return 0
+	}
+	return float64(s.HeapBytes) / float64(s.Limit)
+}
+
+type Event struct {
+	At        time.Time
+	HeapBytes uint64
+	Limit     uint64
+	Evicted   int
+	Capacity  int
+}
+
+type Stats struct {
+	LastShedAt         time.Time
+	HeapBytes          uint64
+	Limit              uint64
+	ShedEvents         uint64
+	EvictedEntries     uint64
+	Capacity           int
+	ConfiguredCapacity int
+	UnderPressure      bool
+}
+
+// Limit returns softLimit when set, otherwise the runtime memory limit
+// configured through GOMEMLIMIT or debug.SetMemoryLimit. Zero means no limit.
+func Limit(softLimit uint64) uint64 {
+	if softLimit > 0 {
+		return softLimit
+	}
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+	return uint64(limit)
+}
+
+func Read(limit uint64) Sample {
+	samples := []metrics.Sample{{Name: liveHeapMetric}, {Name: gcCyclesMetric}}
+	metrics.Read(samples)
+
+	sample := Sample{Limit: limit}
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		sample.HeapBytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		sample.GCCycles = samples[1].Value.Uint64()
+	}
+	return sample
+}
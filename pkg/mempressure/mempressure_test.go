@@ -0,0 +1,37 @@
+package mempressure
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestLimitPrefersSoftLimit(t *testing.T) {
+	if got := Limit(1 << 20); got != 1<<20 {
+		t.Errorf("expected soft limit to win, got %d", got)
+	}
+}
+
+func TestLimitFallsBackToMemoryLimit(t *testing.T) {
+	previous := debug.SetMemoryLimit(64 << 20)
+	defer debug.SetMemoryLimit(previous)
+
+	if got := Limit(0); got != 64<<20 {
+		t.Errorf("expected runtime memory limit, got %d", got)
+	}
+}
+
+func TestReadReportsHeap(t *testing.T) {
+	// The live heap is only measured once a GC cycle has completed.
+	runtime.GC()
+	sample := Read(1 << 30)
+	if sample.HeapBytes == 0 || sample.GCCycles == 0 {
+		t.Errorf("expected non-zero heap bytes and GC cycles, got %+v", sample)
+	}
+	if sample.Limit != 1<<30 {
+		t.Errorf("expected limit to be carried, got %d", sample.Limit)
+	}
+	if ratio := (Sample{HeapBytes: 50, Limit: 100}).Ratio(); ratio != 0.5 {
+		t.Errorf("expected ratio 0.5, got %v", ratio)
+	}
+}
@@ -1,4 +1,4 @@
-package inmemory
+package singleflight
 
 import (
 	"fmt"
@@ -13,12 +13,12 @@ type call[V any] struct {
 	wg    sync.WaitGroup
 }
 
-type singleFlight[V any] struct {
+type Group[V any] struct {
 	m  map[string]*call[V]
 	mu sync.Mutex
 }
 
-func (g *singleFlight[V]) Do(key string, fn func() (V, time.Duration, error)) (V, time.Duration, error) {
+func (g *Group[V]) Do(key string, fn func() (V, time.Duration, error)) (V, time.Duration, error) {
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call[V])
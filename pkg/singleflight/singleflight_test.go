@@ -1,4 +1,4 @@
-package inmemory
+package singleflight
 
 import (
 	"errors"
@@ -9,7 +9,7 @@ import (
 )
 
 func TestDo_ReturnsValue(t *testing.T) {
-	var g singleFlight[int]
+	var g Group[int]
 	v, ttl, err := g.Do("a", func() (int, time.Duration, error) {
 		return 42, 150 * time.Millisecond, nil
 	})
@@ -25,7 +25,7 @@ func TestDo_ReturnsValue(t *testing.T) {
 }
 
 func TestDo_DeduplicatesConcurrent(t *testing.T) {
-	var g singleFlight[string]
+	var g Group[string]
 	var calls int32
 	start := make(chan struct{})
 	const n = 30
@@ -68,7 +68,7 @@ func TestDo_DeduplicatesConcurrent(t *testing.T) {
 }
 
 func TestDo_ErrorPropagation(t *testing.T) {
-	var g singleFlight[int]
+	var g Group[int]
 	var calls int32
 	start := make(chan struct{})
 	const n = 16
@@ -109,7 +109,7 @@ func TestDo_ErrorPropagation(t *testing.T) {
 }
 
 func TestDo_DifferentKeysConcurrent(t *testing.T) {
-	var g singleFlight[int]
+	var g Group[int]
 	var a, b int32
 	start := make(chan struct{})
 	var wg sync.WaitGroup
@@ -157,7 +157,7 @@ func TestDo_DifferentKeysConcurrent(t *testing.T) {
 }
 
 func TestDo_SequentialCallsReexecutes(t *testing.T) {
-	var g singleFlight[int]
+	var g Group[int]
 	var n int32
 
 	v1, _, err1 := g.Do("x", func() (int, time.Duration, error) {